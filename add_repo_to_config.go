@@ -9,49 +9,217 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
+	"time"
+
+	"github-secret-scanning/internal/attachments"
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/ghapi"
+	"github-secret-scanning/internal/repofile"
 )
 
-func parseRepoListFromFile(path string) (string, error) {
+// repoIDCacheEntry is a cached repository ID lookup.
+type repoIDCacheEntry struct {
+	ID        int       `json:"id"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// repoIDCache persists repository name-to-ID lookups between runs, keyed by
+// API host, org and lowercase repository name.
+type repoIDCache struct {
+	path    string
+	host    string
+	ttl     time.Duration
+	Entries map[string]repoIDCacheEntry `json:"entries"`
+	dirty   bool
+}
+
+// loadRepoIDCache opens the cache file at path; an empty path gives an in-memory cache.
+func loadRepoIDCache(path, apiBase string, ttl time.Duration) (*repoIDCache, error) {
+	host := apiBase
+	if u, err := neturl.Parse(apiBase); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	c := &repoIDCache{path: path, host: strings.ToLower(host), ttl: ttl, Entries: map[string]repoIDCacheEntry{}}
+	if path == "" {
+		return c, nil
+	}
 	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read repo file '%s': %w", path, err)
-	}
-	content := strings.TrimSpace(string(data))
-	if content == "" {
-		return "", fmt.Errorf("repo file '%s' is empty", path)
-	}
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	content = strings.ReplaceAll(content, "\r", "\n")
-	seps := func(r rune) bool { return r == '\n' || r == ',' || r == ';' }
-	parts := strings.FieldsFunc(content, seps)
-	uniq := make([]string, 0, len(parts))
-	seen := make(map[string]struct{})
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
+		return c, fmt.Errorf("failed to read repo ID cache '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return c, fmt.Errorf("failed to parse repo ID cache '%s': %w", path, err)
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]repoIDCacheEntry{}
+	}
+	return c, nil
+}
+
+func (c *repoIDCache) key(org, name string) string {
+	return c.host + "/" + strings.ToLower(org) + "/" + strings.ToLower(name)
+}
+
+func (c *repoIDCache) get(org, name string) (int, bool) {
+	e, ok := c.Entries[c.key(org, name)]
+	if !ok || time.Since(e.FetchedAt) > c.ttl {
+		return 0, false
+	}
+	return e.ID, true
+}
+
+func (c *repoIDCache) put(org, name string, id int) {
+	c.Entries[c.key(org, name)] = repoIDCacheEntry{ID: id, FetchedAt: time.Now().UTC()}
+	c.dirty = true
+}
+
+func (c *repoIDCache) invalidate(org, name string) {
+	if _, ok := c.Entries[c.key(org, name)]; ok {
+		delete(c.Entries, c.key(org, name))
+		c.dirty = true
+	}
+}
+
+// save writes the cache back to disk, dropping expired entries.
+func (c *repoIDCache) save() error {
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	for k, e := range c.Entries {
+		if time.Since(e.FetchedAt) > c.ttl {
+			delete(c.Entries, k)
 		}
-		if _, ok := seen[p]; ok {
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repo ID cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write repo ID cache '%s': %w", c.path, err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// listOrgRepoIDs pages through the org repositories and returns lowercase name to ID.
+func listOrgRepoIDs(client *http.Client, apiBase, org, token string) (map[string]int, map[string]string, error) {
+	var repos []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/repos?type=all&per_page=100", apiBase, org), token, &repos); err != nil {
+		return nil, nil, fmt.Errorf("failed to list repos: %w", err)
+	}
+	ids := make(map[string]int)
+	names := make(map[string]string)
+	for _, r := range repos {
+		ids[strings.ToLower(r.Name)] = r.ID
+		names[strings.ToLower(r.Name)] = r.Name
+	}
+	return ids, names, nil
+}
+
+// getRepoID looks up a single repository. found is false on 404.
+func getRepoID(client *http.Client, apiBase, org, token, name string) (id int, canonical string, found bool, err error) {
+	status, body, err := ghapi.Send(client, "GET", fmt.Sprintf("%s/repos/%s/%s", apiBase, org, name), token, nil)
+	if status == http.StatusNotFound {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, fmt.Errorf("failed to get repo '%s': %w", name, err)
+	}
+	var info struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return 0, "", false, fmt.Errorf("failed to parse repo JSON for '%s': %w", name, err)
+	}
+	return info.ID, info.Name, true, nil
+}
+
+// resolveRepoIDs maps names to repository IDs. Cached entries are used first;
+// if more than bulkThreshold names remain, a single paginated org listing
+// resolves them (N/100 calls), otherwise each is fetched individually.
+func resolveRepoIDs(client *http.Client, apiBase, org, token string, names []string, cache *repoIDCache, bulkThreshold int) ([]int, []string, []string) {
+	var ids []int
+	var resolved []string
+	var missing []string
+	var pending []string
+	for _, name := range names {
+		if id, ok := cache.get(org, name); ok {
+			ids = append(ids, id)
+			resolved = append(resolved, name)
 			continue
 		}
-		seen[p] = struct{}{}
-		uniq = append(uniq, p)
+		pending = append(pending, name)
 	}
-	if len(uniq) == 0 {
-		return "", fmt.Errorf("repo file '%s' contains no valid repositories", path)
+	if len(names) > len(pending) {
+		fmt.Printf("Resolved %d repositories from the ID cache\n", len(names)-len(pending))
+	}
+	if len(pending) == 0 {
+		return ids, resolved, missing
+	}
+
+	if len(pending) > bulkThreshold {
+		fmt.Printf("Resolving %d repositories with a paginated org listing\n", len(pending))
+		all, canonical, err := listOrgRepoIDs(client, apiBase, org, token)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for key, id := range all {
+			cache.put(org, canonical[key], id)
+		}
+		for _, name := range pending {
+			id, ok := all[strings.ToLower(name)]
+			if !ok {
+				cache.invalidate(org, name)
+				missing = append(missing, name)
+				continue
+			}
+			ids = append(ids, id)
+			resolved = append(resolved, canonical[strings.ToLower(name)])
+		}
+		return ids, resolved, missing
 	}
-	return strings.Join(uniq, ","), nil
+
+	for _, name := range pending {
+		id, canonical, found, err := getRepoID(client, apiBase, org, token, name)
+		if err != nil {
+			log.Printf("%v", err)
+			missing = append(missing, name)
+			continue
+		}
+		if !found {
+			cache.invalidate(org, name)
+			missing = append(missing, name)
+			continue
+		}
+		cache.put(org, canonical, id)
+		ids = append(ids, id)
+		resolved = append(resolved, canonical)
+	}
+	return ids, resolved, missing
 }
 
 func main() {
 	repo := flag.String("repo", "", "Repository name, list, 'all' or path to the repo list file")
-	repoFile := flag.String("repo-file", "", "Path to a file containing a list of repository names (one per line or comma/semicolon separated)")
+	repoFile := flag.String("repo-file", "", "Path to a file listing repositories: plain text (one per line or comma/semicolon separated), a YAML/JSON list, get_org_repos output or an inventory file")
 	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
 	token := flag.String("token", "", "GitHub API token")
 	configName := flag.String("config", "sample", "Name of the code security configuration template")
 	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	idCachePath := flag.String("id-cache", "workspace/.repo-id-cache.json", "File caching repository name-to-ID lookups (empty to disable)")
+	idCacheTTL := flag.Duration("id-cache-ttl", 24*time.Hour, "How long cached repository IDs stay valid")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	snapshotPath := flag.String("snapshot", "", "File the current attachments are saved to before attaching (default: workspace/<org>-attach-snapshot-<time>.json)")
+	bulkThreshold := flag.Int("bulk-threshold", 10, "Resolve names with one paginated org listing when more than this many are not cached")
 	flag.Parse()
 
 	// GHES_URL env var fallback
@@ -71,7 +239,7 @@ func main() {
 
 	if *repo != "" {
 		if info , err := os.Stat(*repo); err == nil && !info.IsDir() {
-			parsed, perr := parseRepoListFromFile(*repo)
+			parsed, perr := repofile.ParseRepoList(*repo, *org)
 			if perr != nil {
 				log.Fatalf("Error parsing repo file: %v", perr)
 			}
@@ -80,7 +248,7 @@ func main() {
 	}
 
 	if *repo == "" && *repoFile != "" {
-		parsed, perr := parseRepoListFromFile(*repoFile)
+		parsed, perr := repofile.ParseRepoList(*repoFile, *org)
 		if perr != nil {
 			log.Fatalf("Error parsing repo file: %v", perr)
 		}
@@ -95,6 +263,18 @@ func main() {
 
 	// 1. Get all configs for the org
 	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		apiBase = *ghesURL
+	}
+	cache, err := loadRepoIDCache(*idCachePath, apiBase, *idCacheTTL)
+	if err != nil {
+		log.Printf("Warning: %v (continuing without cache)", err)
+		cache, _ = loadRepoIDCache("", apiBase, *idCacheTTL)
+	}
 	var url string 
 	switch githubEndpoint {
 	case "GHEC":
@@ -184,8 +364,9 @@ func main() {
 			for _, r := range repos {
 				repoIDs = append(repoIDs, r.ID)
 				repoNames = append(repoNames, r.Name)
+				cache.put(*org, r.Name, r.ID)
 			}
-			if len(repos) < perPage {
+			if len(repos) < perPage && !strings.Contains(reposResp.Header.Get("Link"), `rel="next"`) {
 				break
 			}
 			page++
@@ -193,100 +374,57 @@ func main() {
 		if len(repoIDs) == 0 {
 			log.Fatalf("No repositories found in organization '%s'", *org)
 		}
-	} else if strings.Contains(*repo, ",") {
-		repoList := strings.Split(*repo, ",")
-		for _, repoName := range repoList {
+	} else {
+		var names []string
+		for _, repoName := range strings.Split(*repo, ",") {
 			repoName = strings.TrimSpace(repoName)
-			if repoName == "" {
-				continue
+			if repoName != "" {
+				names = append(names, repoName)
 			}
-			var repoURL string
-			switch githubEndpoint {
-			case "GHEC":
-				repoURL = fmt.Sprintf("https://api.github.com/repos/%s/%s", *org, repoName)
-			case "GHES", "":
-				repoURL = fmt.Sprintf("%s/repos/%s/%s", *ghesURL, *org, repoName)
-			default:
-				log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
-			}
-			repoReq, err := http.NewRequest("GET", repoURL, nil)
-			if err != nil {
-				log.Fatalf("Failed to create request for %s: %v", repoName, err)
-			}
-			repoReq.Header.Set("Accept", "application/vnd.github+json")
-			repoReq.Header.Set("Authorization", "Bearer "+githubToken)
-			repoReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-			repoResp, err := client.Do(repoReq)
-			if err != nil {
-				log.Fatalf("Request failed for %s: %v", repoName, err)
-			}
-			if repoResp.StatusCode < 200 || repoResp.StatusCode >= 300 {
-				repoBody, _ := ioutil.ReadAll(repoResp.Body)
-				log.Printf("Warning couldn not find repository '%s': %s\n%s\n", repoName, repoResp.Status, string(repoBody))
-				repoResp.Body.Close()
-				continue
-			}
-			repoBody, _ := ioutil.ReadAll(repoResp.Body)
-			var repoInfo struct {
-				ID int `json:"id"`
-				Name string `json:"name"`
-			}
-			if err := json.Unmarshal(repoBody, &repoInfo); err != nil {
-				log.Printf("Failed to parse repo JSON for '%s': %v", repoName, err)
-				continue
-			}
-			repoIDs = append(repoIDs, repoInfo.ID)
-			repoNames = append(repoNames, repoInfo.Name)
-			repoResp.Body.Close()
+		}
+		var missing []string
+		repoIDs, repoNames, missing = resolveRepoIDs(client, apiBase, *org, githubToken, names, cache, *bulkThreshold)
+		for _, name := range missing {
+			log.Printf("Warning could not find repository '%s'", name)
 		}
 		if len(repoIDs) == 0 {
 			log.Fatal("No valid repositories found from the provided list")
 		}
-	} else {
-		var repoURL string
-		switch githubEndpoint {
-		case "GHEC":
-			repoURL = fmt.Sprintf("https://api.github.com/repos/%s/%s", *org, *repo)
-		case "GHES", "":
-			repoURL = fmt.Sprintf("%s/repos/%s/%s", *ghesURL, *org, *repo)
-		default:
-			log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
-		}
-		repoReq, err := http.NewRequest("GET", repoURL, nil)
-		if err != nil {
-			log.Fatalf("Failed to create request for %s: %v", *repo, err)
-		}
-		repoReq.Header.Set("Accept", "application/vnd.github+json")
-		repoReq.Header.Set("Authorization", "Bearer "+githubToken)
-		repoReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-		repoResp, err := client.Do(repoReq)
-		if err != nil {
-			log.Fatalf("Request failed for %s: %v", *repo, err)
-		}
-		defer repoResp.Body.Close()
-		if repoResp.StatusCode < 200 || repoResp.StatusCode >= 300 {
-			repoBody, _ := ioutil.ReadAll(repoResp.Body)
-			log.Fatalf("API error getting repo '%s': %s\n%s\n", *repo, repoResp.Status, string(repoBody))
-		}
-		repoBody, _ := ioutil.ReadAll(repoResp.Body)
-		var repoInfo struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		}
-		if err := json.Unmarshal(repoBody, &repoInfo); err != nil {
-			log.Fatalf("Failed to parse repo JSON for '%s': %v", *repo, err)
+	}
+	if err := cache.save(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Snapshot the current attachments first, there is no other way back from a wrong attach
+	attached, err := attachments.Current(client, apiBase, *org, githubToken, configs)
+	if err != nil {
+		log.Fatalf("Failed to read current attachments for the snapshot: %v", err)
+	}
+	snapshot := attachments.Snapshot{Organization: *org, CreatedAt: time.Now().UTC(), Config: *configName, ConfigID: configID}
+	previous := make(map[string]string)
+	for i, id := range repoIDs {
+		entry := attachments.Repository{Name: repoNames[i], ID: id}
+		if cur, ok := attached[id]; ok {
+			entry.ConfigurationID, entry.Configuration, entry.Status = cur.ConfigurationID, cur.Configuration, cur.Status
 		}
-		repoIDs = []int{repoInfo.ID}
-		repoNames = []string{repoInfo.Name}
+		snapshot.Repositories = append(snapshot.Repositories, entry)
+		previous[repoNames[i]] = entry.Configuration
 	}
+	if *snapshotPath == "" {
+		*snapshotPath = fmt.Sprintf("workspace/%s-attach-snapshot-%s.json", *org, snapshot.CreatedAt.Format("20060102-150405"))
+	}
+	if err := attachments.WriteSnapshot(*snapshotPath, snapshot); err != nil {
+		log.Fatalf("Failed to write snapshot %s: %v", *snapshotPath, err)
+	}
+	fmt.Printf("📸 Current attachments saved to %s (undo with: go run rollback.go -snapshot %s)\n", *snapshotPath, *snapshotPath)
 
 	//Attach configuration
 	var attachURL string
 	switch githubEndpoint {
 	case "GHEC":
-		attachURL = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations/%d/repositories", *org, configID)
+		attachURL = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations/%d/attach", *org, configID)
 	case "GHES", "":
-		attachURL = fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/repositories", *ghesURL, *org, configID)
+		attachURL = fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/attach", *ghesURL, *org, configID)
 	default:
 		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
 	}
@@ -302,12 +440,33 @@ func main() {
 	attachReq.Header.Set("Accept", "application/vnd.github+json")
 	attachReq.Header.Set("Authorization", "Bearer "+githubToken)
 	attachReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	attachReq
+	entry := auditlog.Entry{
+		Actor:         auditlog.Actor(client, apiBase, githubToken),
+		Command:       "add_repo_to_config",
+		Action:        "attach",
+		Org:           *org,
+		Method:        "POST",
+		URL:           attachURL,
+		Config:        *configName,
+		ConfigID:      configID,
+		Repositories:  repoNames,
+		RepositoryIDs: repoIDs,
+		Before:        map[string]interface{}{"configurations": previous, "snapshot": *snapshotPath},
+		After:         map[string]interface{}{"configuration": *configName},
+	}
 	attachResp, err := client.Do(attachReq)
 	if err != nil {
+		entry.Error = err.Error()
+		auditlog.Append(auditlog.Path(*auditLog), entry)
 		log.Fatalf("Request failed for attaching repositories: %v", err)
 	}
 	defer attachResp.Body.Close()
+	attachRespBody, _ := ioutil.ReadAll(attachResp.Body)
+	entry.Status = attachResp.StatusCode
+	if attachResp.StatusCode != 202 {
+		entry.Error = strings.TrimSpace(string(attachRespBody))
+	}
+	auditlog.Append(auditlog.Path(*auditLog), entry)
 	if attachResp.StatusCode == 202 {
 		if *repo == "all" {
 			fmt.Printf("All repositories in organization '%s' have been attached to configuration '%s' (ID: %d).\n", *org, *configName, configID)
@@ -323,7 +482,14 @@ func main() {
 		}
 		fmt.Println(string(attachRespBody))
 	} else {
+		// A stale cached ID is a likely cause, so force a fresh lookup next time.
+		for _, name := range repoNames {
+			cache.invalidate(*org, name)
+		}
+		if err := cache.save(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 		fmt.Fprintf(os.Stderr, "API error: %s\n%s\n", attachResp.Status, string(attachRespBody))
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -1,125 +1,48 @@
-## Needs test!!!
-
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
+
+	"github-secret-scanning/internal/ghapi"
 )
 
-const apiVersion = "2022-11-28"
+type propertyValue struct {
+	PropertyName string      `json:"property_name"`
+	Value        interface{} `json:"value"`
+}
 
 type repoInfo struct {
-	Repository struct {
-		Name string `json:"name"`
-	} `json:"repository"`
-	Properties []struct {
-		PropertyName  string `json:"property_name"`
-		Value interface{} `json:"value"`
-	} `json:"properties"`
+	RepositoryName string          `json:"repository_name"`
+	Properties     []propertyValue `json:"properties"`
 }
 
 type repoListItem struct {
-	Name string `json:"name"`
-	Private bool   `json:"private"`
-	Archived bool  `json:"archived"`
+	Name     string `json:"name"`
+	Private  bool   `json:"private"`
+	Archived bool   `json:"archived"`
 }
 
-func normalizeBaseURL(raw string) (string, error) {
-	if raw == "" {
-		return "https://api.github.com", nil	
-	}
-	if strings.HasSuffix(raw, "/") {
-		raw = strings.TrimSuffix(raw, "/")
-	}
-	return raw, 
-	
-	u, err := url.Parse(raw)
-	if err != nil || u.Scheme == "" || u.Host == "" {
-		return fmt.Sprintf("https://%s/api/v3", strings.TrimPrefix(strings.TrimPrefix(raw, "https://"), "http://")), nil
-	}
-	return fmt.Sprintf("%s://%s/api/v3", u.Scheme, u.Host), nil
-}
-
-
-func fetchOrgRepos(client *http.Client, baseURL, org, authHeader string) ([]repoListItem, error) {
+func fetchOrgRepos(client *http.Client, baseURL, org, token string) ([]repoListItem, error) {
 	var repos []repoListItem
-	page := 1
-	perPage := 100
-	for {
-		url := fmt.Sprintf("%s/orgs/%s/repos?per_page=%d&page=%d", baseURL, org, perPage, page)	
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Authorization", authHeader)
-		req.Header.Set("Accept", "application/vnd.github+json")
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		if resp.StatusCode != 200 {
-			b, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("list repos error: %s %s", resp.Status, b)
-		}
-		var batch []repoListItem
-		if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
-			resp.Body.Close()
-			return nil, err
-		}
-		resp.Body.Close()
-		if len(batch) == 0 {
-			break
-		}
-		repos = append(repos, batch...)
-		if len(batch) < perPage {
-			break
-		}
-		page++
-	 }
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/repos?per_page=100", baseURL, org), token, &repos); err != nil {
+		return nil, fmt.Errorf("list repos error: %w", err)
+	}
 	return repos, nil
 }
 
-func fetchRepoProperties(client *http.Client, baseURL, org, repo, authHeader string) ([]struct {
-		PropertyName  string `json:"property_name"`
-		Value interface{} `json:"value"`
-	}, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/properties", baseURL, org, repo)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", authHeader)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("list repo properties error: %s %s", resp.Status, b)
+func fetchRepoProperties(client *http.Client, baseURL, org, repo, token string) ([]propertyValue, error) {
+	var props []propertyValue
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/repos/%s/%s/properties/values", baseURL, org, repo), token, &props); err != nil {
+		return nil, fmt.Errorf("list repo properties error: %w", err)
 	}
-	var props []struct {
-		PropertyName  string `json:"property_name"`
-		Value interface{} `json:"value"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&props); err != nil {
-		resp.Body.Close()
-		return nil, err
-	}
-	resp.Body.Close()
 	return props, nil
 }
 
-func main() { 
+func main() {
 	org := flag.String("org", "", "GitHub organization name (required)")
 	tokenFlag := flag.String("token", "", "GitHub API token (or set GITHUB_TOKEN_ORG / GITHUB_TOKEN) (required)")
 	ghesURL := flag.String("ghes-url", "", "Base URL for GHES API (ignored for GHEC)")
@@ -134,7 +57,7 @@ func main() {
 	publicProdFile := flag.String("public-prod-outFile", "", "Write matched public repositories as a single comma-separated line to this file (default workspace/<org>-public-prod.txt if empty)")
 	flag.Parse()
 
-	// GHES_URL env var fallback (added even though file has known syntax issues elsewhere)
+	// GHES_URL env var fallback
 	if *ghesURL == "" {
 		if envURL := os.Getenv("GHES_URL"); envURL != "" {
 			trimmed := strings.TrimRight(envURL, "/")
@@ -170,16 +93,20 @@ func main() {
 		fmt.Fprintln(os.Stderr, "-token is required (or set GITHUB_TOKEN_ORG / GITHUB_TOKEN)")
 		os.Exit(1)
 	}
-	
+
 	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
 	var baseURL string
-	swtich githubEndpoint {
+	switch githubEndpoint {
 	case "GHEC":
 		baseURL = "https://api.github.com"
 	case "GHES":
+		if *ghesURL == "" {
+			fmt.Fprintln(os.Stderr, "Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+			os.Exit(1)
+		}
 		baseURL = strings.TrimRight(*ghesURL, "/")
 	default:
-		fmt.Fprintln(os.Stderr, "GITHUB ENDOPOINT must be set to GHEC or GHES, or left unset for GHES as default")
+		fmt.Fprintln(os.Stderr, "GITHUB_ENDPOINT must be set to GHEC or GHES")
 		os.Exit(1)
 	}
 
@@ -192,19 +119,21 @@ func main() {
 			v = strings.TrimSpace(v)
 			if v != "" {
 				accepted[strings.ToLower(v)] = struct{}{}
+			}
 		}
 	}
-
-	isPublic := strings.Contains(baseURL, "api.github.com")
-	sendVersionHeader := isPublic
-	authPrefix := "token "
-	if isPublic {
-		authPrefix = "Bearer "
+	// matches reports whether a property value is one of the accepted values
+	matches := func(value interface{}) (string, bool) {
+		valStr := strings.ToLower(fmt.Sprint(value))
+		if accepted != nil {
+			_, match := accepted[valStr]
+			return valStr, match
+		}
+		return valStr, valStr == targetValue
 	}
-	authHeader := authPrefix + token
 
 	if *debug {
-		fmt.Fprintf(os.Stderr, "Using API base URL: %s\n public: %v\n auth: %s\n propsEndpointMode: %s\n", baseURL, isPublic, strings.Fields(authPrefix)[0], map[bool] string{true: "repo", false: "org"}[*fallback])
+		fmt.Fprintf(os.Stderr, "Using API base URL: %s\n propsEndpointMode: %s\n", baseURL, map[bool]string{true: "repo", false: "org"}[*fallback])
 	}
 
 	matched := 0
@@ -213,88 +142,39 @@ func main() {
 	publicProdRepos := make([]string, 0, 64)
 
 	if !*fallback {
-		page := 1
-		perPage := 100
-		for {
-			url := fmt.Sprintf("%s/orgs/%s/properties/values?per_page=%d&page=%d", baseURL, *org, perPage, page)
-			req, err := http.NewRequest("GET", url, nil)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "request build error", err)
-				os.Exit(1)
-			}
-			req.Header.Set("Authorization", authHeader)
-			req.Header.Set("Accept", "application/vnd.github+json")
-			if sendVersionHeader {
-				req.Header.Set("X-GitHub-Api-Version", apiVersion)
-			}
-			resp, err := client.Do(req)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "request error", err)
-				os.Exit(1)
+		var batch []repoInfo
+		err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/properties/values?per_page=100", baseURL, *org), token, &batch)
+		if err != nil && strings.HasPrefix(err.Error(), "API error: 404") {
+			if *debug {
+				fmt.Fprintln(os.Stderr, "Org properties endpoint not found, switching to repo fallback")
 			}
-			if resp.StatusCode == 404 {
-				resp.Body.Close()
-				if *debug {
-					fmt.Fprintln(os.Stderr, "Org properties endpoint not found, switching to repo fallback")
+			*fallback = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "list org properties error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, r := range batch {
+			checked++
+			for _, p := range r.Properties {
+				if p.PropertyName != *propName {
+					continue
 				}
-				*fallback = true
-				break
-			}
-			if resp.StatusCode == 401 {
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				fmt.Fprintf(os.Stderr, "authentication error: %s %s\n", resp.Status, body)
-				os.Exit(1)
-			}
-			if resp.StatusCode != 200 {
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				fmt.Fprintf(os.Stderr, "list org properties error: %s %s\n", resp.Status, body)
-				os.Exit(1)
-			}
-			var batch []repoInfo
-			if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
-				resp.Body.Close()
-				fmt.Fprintln(os.Stderr, "decode error", err)
-				os.Exit(1)
-			}
-			resp.Body.Close()
-			if len(batch) == 0 {
-				break
-			}
-			for _, r := range batch {
-				checked++
-				printed := false
-				for _, p := range r.Properties {
-					if p.PropertyName == *propName {
-						valStr := strings.ToLower(fmt.Sprint(p.Value))
-						match := false
-						if accepted != nil {
-							_, match = accepted[valStr]
-						} else {
-							match = (valStr == targetValue)
-						}
-						if *showAll {
-							fmt.Printf(os.Stderr, "repo: %s value: %s match: %v\n", r.Repository.Name, valStr, match)
-						}
-					} if match {
-						fmt.Println(r.Repository.Name)
-						matched++
-						matchedNames = append(matchedNames, r.Repository.Name)
+				valStr, match := matches(p.Value)
+				if *showAll {
+					fmt.Fprintf(os.Stderr, "repo: %s value: %s match: %v\n", r.RepositoryName, valStr, match)
+				}
+				if match {
+					fmt.Println(r.RepositoryName)
+					matched++
+					matchedNames = append(matchedNames, r.RepositoryName)
 				}
-				printed = true
-				break
-			}
-		} 
-			if len(batch) < perPage {
 				break
 			}
-			page++
 		}
 	}
 
 	if *fallback {
-		repos, err := fetchOrgRepos(client, baseURL, *org, authHeader)
+		repos, err := fetchOrgRepos(client, baseURL, *org, token)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "list repos error", err)
 			os.Exit(1)
@@ -306,7 +186,7 @@ func main() {
 			}
 
 			if !*publicOnly {
-				props, err := fetchRepoProperties(client, baseURL, *org, repo.Name, authHeader)
+				props, err := fetchRepoProperties(client, baseURL, *org, repo.Name, token)
 				if err != nil {
 					if *debug {
 						fmt.Fprintf(os.Stderr, "skip repo %s %v\n", repo.Name, err)
@@ -318,15 +198,9 @@ func main() {
 				isProdRepo := false
 				for _, p := range props {
 					if p.PropertyName == *propName {
-						valStr := strings.ToLower(fmt.Sprint(p.Value))
-						match := false
-						if accepted != nil {
-							_, match = accepted[valStr]
-						} else {
-							match = valStr == targetValue
-						}
+						valStr, match := matches(p.Value)
 						if *showAll {
-							fmt.Printf(os.Stderr, "repo: %s value: %s match: %v\n", repo.Name, valStr, match)
+							fmt.Fprintf(os.Stderr, "repo: %s value: %s match: %v\n", repo.Name, valStr, match)
 						}
 						if match {
 							fmt.Println(repo.Name)
@@ -343,7 +217,7 @@ func main() {
 					publicProdRepos = append(publicProdRepos, repo.Name)
 				}
 				if !found && *showAll {
-					fmt.Printf(os.Stderr, "repo: %s property %s not set\n", repo.Name, *propName)
+					fmt.Fprintf(os.Stderr, "repo: %s property %s not set\n", repo.Name, *propName)
 				}
 			}
 		}
@@ -375,7 +249,15 @@ func main() {
 	}
 
 	if matched == 0 {
-		fmt.Fprintf(os.Stderr, "No repositories matched. Checked: %d property: %s value(s): %s. Use -showAll -debug for diagnostics\n", checked, *propName, func() string {if accepted!=nil { keys:= make([]string, 0, len(accepted)); for k := range accepted { keys = append(keys, k) }; return strings.Join(keys, ",") } return targetValue}())
+		fmt.Fprintf(os.Stderr, "No repositories matched. Checked: %d property: %s value(s): %s. Use -showAll -debug for diagnostics\n", checked, *propName, func() string {
+			if accepted != nil {
+				keys := make([]string, 0, len(accepted))
+				for k := range accepted {
+					keys = append(keys, k)
+				}
+				return strings.Join(keys, ",")
+			}
+			return targetValue
+		}())
 	}
 }
-}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github-secret-scanning/internal/auditlog"
+)
+
+// parseSince accepts a window like 30d or 12h, or a date like 2025-01-31.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return time.Time{}, fmt.Errorf("invalid window '%s'", s)
+		}
+		return time.Now().Add(-time.Duration(days) * 24 * time.Hour), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid window '%s': use 30d, 12h or a date like 2025-01-31", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// matchAny reports whether pattern (a path.Match glob, case-insensitive) matches one of values.
+func matchAny(pattern string, values ...string) bool {
+	pattern = strings.ToLower(pattern)
+	for _, v := range values {
+		if ok, _ := path.Match(pattern, strings.ToLower(v)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type auditFilter struct {
+	org, config, repo, actor, action, command, result string
+	since, until                                      time.Time
+}
+
+func (f auditFilter) matches(e auditlog.Entry) bool {
+	if f.org != "" && !strings.EqualFold(f.org, e.Org) {
+		return false
+	}
+	if f.config != "" && !matchAny(f.config, e.Config) && f.config != strconv.Itoa(e.ConfigID) {
+		return false
+	}
+	if f.repo != "" && !matchAny(f.repo, e.Repositories...) {
+		return false
+	}
+	if f.actor != "" && !matchAny(f.actor, e.Actor, e.LocalUser) {
+		return false
+	}
+	if f.action != "" && !matchAny(f.action, e.Action) {
+		return false
+	}
+	if f.command != "" && !matchAny(f.command, e.Command) {
+		return false
+	}
+	if f.result != "" && !strings.EqualFold(f.result, e.Result) {
+		return false
+	}
+	if !f.since.IsZero() && e.Timestamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && e.Timestamp.After(f.until) {
+		return false
+	}
+	return true
+}
+
+// readAudit returns the entries of the audit log that match f, oldest first.
+func readAudit(logPath string, f auditFilter) ([]auditlog.Entry, error) {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []auditlog.Entry
+	scanner := bufio.NewScanner(file)
+	// Attaching a whole org lists every repository on one line
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e auditlog.Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if f.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func summarizeRepos(repos []string, pattern string) string {
+	if len(repos) == 0 {
+		return "-"
+	}
+	// Show the repositories the -repo filter matched first
+	shown := repos
+	if pattern != "" {
+		var matched, rest []string
+		for _, r := range repos {
+			if matchAny(pattern, r) {
+				matched = append(matched, r)
+			} else {
+				rest = append(rest, r)
+			}
+		}
+		shown = append(matched, rest...)
+	}
+	if len(shown) > 3 {
+		return fmt.Sprintf("%s (+%d)", strings.Join(shown[:3], ","), len(shown)-3)
+	}
+	return strings.Join(shown, ",")
+}
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run audit.go show [-log workspace/audit.jsonl] [-org org] [-config name|id] [-repo glob] [-actor login] [-action attach] [-since 30d] [-result failure] [-format table|json]")
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "show" {
+		showUsage()
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	logFlag := fs.String("log", "", "Audit log to read (default: AUDIT_LOG or workspace/audit.jsonl)")
+	org := fs.String("org", "", "Only entries for this organization")
+	config := fs.String("config", "", "Only entries for this configuration name (glob) or ID")
+	repo := fs.String("repo", "", "Only entries touching a repository matching this glob (e.g. prod-*)")
+	actor := fs.String("actor", "", "Only entries by this GitHub login or local user (glob)")
+	action := fs.String("action", "", "Only this action: attach, detach, create_configuration, update_configuration, set_default_for_new_repos, update_repo_security_settings, update_default_setup, resolve_secret_alert")
+	command := fs.String("command", "", "Only entries written by this command (e.g. serve)")
+	result := fs.String("result", "", "Only success or failure")
+	since := fs.String("since", "", "Only entries newer than a window (30d, 12h) or a date (2025-01-31)")
+	until := fs.String("until", "", "Only entries older than a window or a date")
+	format := fs.String("format", "table", "Output format: table or json (one entry per line, with before/after values)")
+	limit := fs.Int("limit", 0, "Only show the newest N matching entries (0 for all)")
+	fs.Usage = func() {
+		showUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[2:])
+
+	f := auditFilter{org: *org, config: *config, repo: *repo, actor: *actor, action: *action, command: *command, result: *result}
+	var err error
+	if *since != "" {
+		if f.since, err = parseSince(*since); err != nil {
+			log.Fatalf("Invalid -since: %v", err)
+		}
+	}
+	if *until != "" {
+		if f.until, err = parseSince(*until); err != nil {
+			log.Fatalf("Invalid -until: %v", err)
+		}
+	}
+	if *format != "table" && *format != "json" {
+		log.Fatalf("Invalid -format '%s': must be table or json", *format)
+	}
+
+	logPath := auditlog.Path(*logFlag)
+	entries, err := readAudit(logPath, f)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Fatalf("No audit log at %s yet, it is written by the commands that change GitHub state", logPath)
+		}
+		log.Fatalf("Failed to read %s: %v", logPath, err)
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			enc.Encode(e)
+		}
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("No matching audit entries.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME (UTC)\tACTOR\tACTION\tORG\tCONFIG\tREPOSITORIES\tRESULT")
+	failures := 0
+	for _, e := range entries {
+		cfg := e.Config
+		if cfg == "" && e.ConfigID != 0 {
+			cfg = "#" + strconv.Itoa(e.ConfigID)
+		}
+		if cfg == "" {
+			cfg = "-"
+		}
+		res := "✅"
+		if e.Result != "success" {
+			res = "❌"
+			if e.Status != 0 {
+				res += " " + strconv.Itoa(e.Status)
+			}
+			failures++
+		}
+		if e.OverrideReason != "" {
+			res += " (policy overridden)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Timestamp.UTC().Format("2006-01-02 15:04:05"), e.Actor, e.Action, e.Org, cfg, summarizeRepos(e.Repositories, *repo), res)
+	}
+	w.Flush()
+	fmt.Printf("\n%d entries (%d failed). Use -format json for URLs and before/after values.\n", len(entries), failures)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/ghapi"
+	"github-secret-scanning/internal/repofile"
+)
+
+// DefaultSetup is the code scanning default setup of a repository.
+type DefaultSetup struct {
+	State       string   `json:"state"`
+	Languages   []string `json:"languages"`
+	QuerySuite  string   `json:"query_suite"`
+	RunnerType  string   `json:"runner_type"`
+	RunnerLabel string   `json:"runner_label"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+// DefaultSetupUpdate is the PATCH body; only non-empty fields are sent.
+type DefaultSetupUpdate struct {
+	State       string   `json:"state,omitempty"`
+	Languages   []string `json:"languages,omitempty"`
+	QuerySuite  string   `json:"query_suite,omitempty"`
+	RunnerType  string   `json:"runner_type,omitempty"`
+	RunnerLabel string   `json:"runner_label,omitempty"`
+}
+
+// getDefaultSetup reads /repos/{org}/{repo}/code-scanning/default-setup.
+func getDefaultSetup(client *http.Client, apiBase, org, repo, token string) (*DefaultSetup, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/code-scanning/default-setup", apiBase, org, repo)
+	req, err := ghapi.NewRequest("GET", url, token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+	}
+	var setup DefaultSetup
+	if err := json.Unmarshal(body, &setup); err != nil {
+		return nil, fmt.Errorf("failed to parse default setup JSON: %w", err)
+	}
+	return &setup, nil
+}
+
+// updateDefaultSetup PATCHes the default setup. GitHub answers 202 with the
+// run that applies the change, or 200 when nothing had to run.
+func updateDefaultSetup(client *http.Client, apiBase, org, repo, token string, update DefaultSetupUpdate) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/code-scanning/default-setup", apiBase, org, repo)
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	req, err := ghapi.NewRequest("PATCH", url, token, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+	}
+	var result struct {
+		RunURL string `json:"run_url"`
+	}
+	_ = json.Unmarshal(body, &result)
+	return result.RunURL, nil
+}
+
+func describeSetup(s *DefaultSetup) string {
+	langs := strings.Join(s.Languages, ",")
+	if langs == "" {
+		langs = "-"
+	}
+	runner := s.RunnerType
+	if s.RunnerLabel != "" {
+		runner += " (" + s.RunnerLabel + ")"
+	}
+	if runner == "" {
+		runner = "-"
+	}
+	suite := s.QuerySuite
+	if suite == "" {
+		suite = "-"
+	}
+	return fmt.Sprintf("state=%s languages=%s query_suite=%s runner=%s", s.State, langs, suite, runner)
+}
+
+// sortedLanguages joins languages in sorted order, so the same set compares equal whatever order GitHub returns.
+func sortedLanguages(langs []string) string {
+	sorted := append([]string(nil), langs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// planChanges lists the fields of update that differ from the current setup.
+func planChanges(cur *DefaultSetup, update DefaultSetupUpdate) []string {
+	var diffs []string
+	if update.State != "" && update.State != cur.State {
+		diffs = append(diffs, fmt.Sprintf("state: %s -> %s", cur.State, update.State))
+	}
+	if len(update.Languages) > 0 && sortedLanguages(update.Languages) != sortedLanguages(cur.Languages) {
+		diffs = append(diffs, fmt.Sprintf("languages: [%s] -> [%s]", sortedLanguages(cur.Languages), sortedLanguages(update.Languages)))
+	}
+	if update.QuerySuite != "" && update.QuerySuite != cur.QuerySuite {
+		diffs = append(diffs, fmt.Sprintf("query_suite: %s -> %s", cur.QuerySuite, update.QuerySuite))
+	}
+	if update.RunnerType != "" && update.RunnerType != cur.RunnerType {
+		diffs = append(diffs, fmt.Sprintf("runner_type: %s -> %s", cur.RunnerType, update.RunnerType))
+	}
+	if update.RunnerLabel != "" && update.RunnerLabel != cur.RunnerLabel {
+		diffs = append(diffs, fmt.Sprintf("runner_label: %s -> %s", cur.RunnerLabel, update.RunnerLabel))
+	}
+	return diffs
+}
+
+func main() {
+	action := flag.String("action", "get", "get: report the default setup, set: update it")
+	repo := flag.String("repo", "", "Repository name, comma-separated list, 'all' or path to a repo list file")
+	repoFile := flag.String("repo-file", "", "Path to a repo list file (same formats as add_repo_to_config -repo-file)")
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	state := flag.String("state", "", "set: configured or not-configured")
+	languages := flag.String("languages", "", "set: comma-separated CodeQL languages (e.g. go,javascript-typescript)")
+	querySuite := flag.String("query-suite", "", "set: default or extended")
+	runnerType := flag.String("runner-type", "", "set: standard or labeled")
+	runnerLabel := flag.String("runner-label", "", "set: runner label when -runner-type labeled")
+	jsonOut := flag.Bool("json", false, "get: print the default setup per repository as JSON")
+	dryRun := flag.Bool("dry-run", false, "set: only show the planned changes")
+	yes := flag.Bool("yes", false, "set: apply without asking for confirmation")
+	auditLog := flag.String("audit-log", "", "set: JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+
+	if *repo != "" {
+		if info, err := os.Stat(*repo); err == nil && !info.IsDir() {
+			parsed, perr := repofile.ParseRepoList(*repo, *org)
+			if perr != nil {
+				log.Fatalf("Error parsing repo file: %v", perr)
+			}
+			*repo = parsed
+		}
+	}
+	if *repo == "" && *repoFile != "" {
+		parsed, perr := repofile.ParseRepoList(*repoFile, *org)
+		if perr != nil {
+			log.Fatalf("Error parsing repo file: %v", perr)
+		}
+		*repo = parsed
+	}
+	if *repo == "" || *org == "" || (*action != "get" && *action != "set") {
+		log.Fatal("Usage: go run code_scanning_default_setup.go -org <org> -repo <repo|list|all|file> [-action get|set] [-state configured] [-languages go,python] [-query-suite extended] [-runner-type labeled -runner-label code-scanning]")
+	}
+
+	update := DefaultSetupUpdate{
+		State:       *state,
+		QuerySuite:  *querySuite,
+		RunnerType:  *runnerType,
+		RunnerLabel: *runnerLabel,
+	}
+	for _, l := range strings.Split(*languages, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			update.Languages = append(update.Languages, l)
+		}
+	}
+	if *action == "set" {
+		if update.State == "" && len(update.Languages) == 0 && update.QuerySuite == "" && update.RunnerType == "" && update.RunnerLabel == "" {
+			log.Fatal("-action set needs at least one of -state, -languages, -query-suite, -runner-type, -runner-label")
+		}
+		if update.State != "" && update.State != "configured" && update.State != "not-configured" {
+			log.Fatalf("Invalid -state '%s': must be configured or not-configured", update.State)
+		}
+		if update.QuerySuite != "" && update.QuerySuite != "default" && update.QuerySuite != "extended" {
+			log.Fatalf("Invalid -query-suite '%s': must be default or extended", update.QuerySuite)
+		}
+		if update.RunnerType != "" && update.RunnerType != "standard" && update.RunnerType != "labeled" {
+			log.Fatalf("Invalid -runner-type '%s': must be standard or labeled", update.RunnerType)
+		}
+		if update.RunnerType == "labeled" && update.RunnerLabel == "" {
+			log.Fatal("-runner-label is required with -runner-type labeled")
+		}
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	var repoNames []string
+	if *repo == "all" {
+		names, err := ghapi.ListOrgRepoNames(client, apiBase, *org, githubToken)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		repoNames = names
+	} else {
+		for _, name := range strings.Split(*repo, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				repoNames = append(repoNames, name)
+			}
+		}
+	}
+
+	current := make(map[string]*DefaultSetup)
+	var targets []string
+	configured := 0
+	for _, name := range repoNames {
+		setup, err := getDefaultSetup(client, apiBase, *org, name, githubToken)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠️  %s: %v\n", name, err)
+			continue
+		}
+		current[name] = setup
+		if setup.State == "configured" {
+			configured++
+		}
+		if *action == "get" {
+			if !*jsonOut {
+				icon := "❌"
+				if setup.State == "configured" {
+					icon = "✅"
+				}
+				fmt.Printf("  %s %s: %s\n", icon, name, describeSetup(setup))
+			}
+			continue
+		}
+		diffs := planChanges(setup, update)
+		if len(diffs) == 0 {
+			fmt.Printf("  %s: already up to date\n", name)
+			continue
+		}
+		fmt.Printf("  %s:\n", name)
+		for _, d := range diffs {
+			fmt.Printf("    > %s\n", d)
+		}
+		targets = append(targets, name)
+	}
+
+	if *action == "get" {
+		if *jsonOut {
+			out, err := json.MarshalIndent(current, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to marshal JSON: %v", err)
+			}
+			fmt.Println(string(out))
+			return
+		}
+		fmt.Printf("Default setup configured on %d of %d repositories.\n", configured, len(current))
+		return
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("No changes needed.")
+		return
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d repositories would be updated.\n", len(targets))
+		return
+	}
+	if !*yes {
+		fmt.Printf("Apply these changes to %d repositories? (y/N): ", len(targets))
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if answer != "y\n" && answer != "Y\n" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+	failed := 0
+	actor := auditlog.Actor(client, apiBase, githubToken)
+	for _, name := range targets {
+		runURL, err := updateDefaultSetup(client, apiBase, *org, name, githubToken, update)
+		entry := auditlog.Entry{
+			Actor:        actor,
+			Command:      "code_scanning_default_setup",
+			Action:       "update_default_setup",
+			Org:          *org,
+			Method:       "PATCH",
+			URL:          fmt.Sprintf("%s/repos/%s/%s/code-scanning/default-setup", apiBase, *org, name),
+			Repositories: []string{name},
+			Before:       current[name],
+			After:        update,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		auditlog.Append(auditlog.Path(*auditLog), entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ❌ %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		if runURL != "" {
+			fmt.Printf("  ✅ %s: update started (%s)\n", name, runURL)
+		} else {
+			fmt.Printf("  ✅ %s: updated\n", name)
+		}
+	}
+	fmt.Printf("Updated %d of %d repositories.\n", len(targets)-failed, len(targets))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
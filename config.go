@@ -0,0 +1,687 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/codesecurity"
+	"github-secret-scanning/internal/configyaml"
+	"github-secret-scanning/internal/ghapi"
+)
+
+// instance is one side of a copy: an organization on GHEC or on a GHES server.
+type instance struct {
+	Org      string
+	Endpoint string
+	APIBase  string
+	Token    string
+}
+
+func (i instance) String() string {
+	if i.Endpoint == "GHES" {
+		return fmt.Sprintf("%s (GHES %s)", i.Org, i.APIBase)
+	}
+	return fmt.Sprintf("%s (GHEC)", i.Org)
+}
+
+// ghecOnlyFields are configuration fields GHES does not accept; they are left out when the target is GHES.
+var ghecOnlyFields = map[string]func(*codesecurity.Config) *string{
+	"secret_scanning_generic_secrets":           func(c *codesecurity.Config) *string { return &c.SecretScanningGenericSecrets },
+	"secret_scanning_delegated_alert_dismissal": func(c *codesecurity.Config) *string { return &c.SecretScanningDelegatedAlertDismissal },
+}
+
+// builtinRoleMax is the highest built-in repository role ID (1 read ... 5 admin). Those IDs are the same in every
+// organization; custom roles and teams are not.
+const builtinRoleMax = 5
+
+// teamSlugs maps team IDs of an organization to their slugs.
+func teamSlugs(client *http.Client, side instance) (map[int]string, error) {
+	var teams []struct {
+		ID   int    `json:"id"`
+		Slug string `json:"slug"`
+	}
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/teams?per_page=100", side.APIBase, side.Org), side.Token, &teams); err != nil {
+		return nil, err
+	}
+	slugs := make(map[int]string)
+	for _, t := range teams {
+		slugs[t.ID] = t.Slug
+	}
+	return slugs, nil
+}
+
+// adaptConfig rewrites a source configuration for the target: team reviewers are matched by slug, reviewers that
+// only exist in the source organization are dropped, and fields the target does not support are left out.
+// It returns one note per adaptation.
+func adaptConfig(cfg codesecurity.Config, target instance, sourceTeams, targetTeams map[int]string) (codesecurity.Config, []string) {
+	var notes []string
+	cfg.ID, cfg.TargetType = 0, ""
+
+	if opts := cfg.SecretScanningDelegatedBypassOptions; opts != nil && len(opts.Reviewers) > 0 {
+		targetIDs := make(map[string]int)
+		for id, slug := range targetTeams {
+			targetIDs[slug] = id
+		}
+		var reviewers []codesecurity.Reviewer
+		for _, r := range opts.Reviewers {
+			switch {
+			case r.ReviewerType == "TEAM":
+				slug, ok := sourceTeams[r.ReviewerID]
+				if !ok {
+					notes = append(notes, fmt.Sprintf("bypass reviewer team %d not found in the source organization, dropped", r.ReviewerID))
+					continue
+				}
+				id, ok := targetIDs[slug]
+				if !ok {
+					notes = append(notes, fmt.Sprintf("bypass reviewer team '%s' does not exist in %s, dropped", slug, target.Org))
+					continue
+				}
+				if id != r.ReviewerID {
+					notes = append(notes, fmt.Sprintf("bypass reviewer team '%s': ID %d -> %d", slug, r.ReviewerID, id))
+				}
+				reviewers = append(reviewers, codesecurity.Reviewer{ReviewerID: id, ReviewerType: "TEAM"})
+			case r.ReviewerType == "ROLE" && r.ReviewerID > builtinRoleMax:
+				notes = append(notes, fmt.Sprintf("bypass reviewer custom role %d is specific to the source organization, dropped", r.ReviewerID))
+			default:
+				reviewers = append(reviewers, r)
+			}
+		}
+		cfg.SecretScanningDelegatedBypassOptions = &codesecurity.SecretScanningDelegatedBypassOptions{Reviewers: reviewers}
+		if len(reviewers) == 0 {
+			cfg.SecretScanningDelegatedBypassOptions = nil
+			if cfg.SecretScanningDelegatedBypass == "enabled" {
+				cfg.SecretScanningDelegatedBypass = "disabled"
+				notes = append(notes, "secret_scanning_delegated_bypass: enabled -> disabled (no reviewer exists in the target)")
+			}
+		}
+	}
+
+	if target.Endpoint == "GHES" {
+		// GHEC splits GitHub Advanced Security into two products, GHES only knows enabled/disabled
+		if cfg.AdvancedSecurity == "code_security" || cfg.AdvancedSecurity == "secret_protection" {
+			notes = append(notes, fmt.Sprintf("advanced_security: %s -> enabled (GHES)", cfg.AdvancedSecurity))
+			cfg.AdvancedSecurity = "enabled"
+		}
+		names := make([]string, 0, len(ghecOnlyFields))
+		for name := range ghecOnlyFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field := ghecOnlyFields[name](&cfg)
+			if *field != "" {
+				notes = append(notes, fmt.Sprintf("%s: not supported on GHES, left out", name))
+				*field = ""
+			}
+		}
+	}
+	return cfg, notes
+}
+
+// defaultFor returns the default_for_new_repos setting of a configuration, "none" when it is not a default.
+func defaultFor(client *http.Client, side instance, configID int) (string, error) {
+	var defaults []codesecurity.Default
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations/defaults", side.APIBase, side.Org), side.Token, &defaults); err != nil {
+		return "", err
+	}
+	for _, d := range defaults {
+		if d.Configuration.ID == configID {
+			return d.DefaultForNewRepos, nil
+		}
+	}
+	return "none", nil
+}
+
+// resolveInstance picks the endpoint, API base and token of an organization. side is the flag prefix of one side
+// of a copy ("from" or "to"), or "" for commands that work on one organization.
+func resolveInstance(org, endpoint, ghesURL, token, side string) instance {
+	flagName := func(name string) string {
+		if side == "" {
+			return "-" + name
+		}
+		return "-" + side + "-" + name
+	}
+	if endpoint == "" && ghesURL != "" {
+		endpoint = "GHES"
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("GITHUB_ENDPOINT")
+	}
+	if ghesURL == "" {
+		ghesURL = os.Getenv("GHES_URL")
+	}
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		log.Fatalf("GitHub token for %s must be provided via %s or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable", org, flagName("token"))
+	}
+	inst := instance{Org: org, Token: token}
+	switch strings.ToUpper(endpoint) {
+	case "GHEC":
+		inst.Endpoint, inst.APIBase = "GHEC", "https://api.github.com"
+	case "GHES", "":
+		if ghesURL == "" {
+			log.Fatalf("Set %s or GHES_URL when %s is on GHES", flagName("ghes-url"), org)
+		}
+		inst.Endpoint, inst.APIBase = "GHES", strings.TrimRight(ghesURL, "/")
+	default:
+		log.Fatalf("%s must be GHEC or GHES. Got '%s'", flagName("endpoint"), endpoint)
+	}
+	return inst
+}
+
+// policyFlags are the guardrail flags of the commands that change a configuration.
+type policyFlags struct {
+	path     *string
+	none     *bool
+	override *string
+}
+
+func addPolicyFlags(fs *flag.FlagSet) policyFlags {
+	return policyFlags{
+		path:     fs.String("policy", "", "Guardrails file checked against the diff (default: UPDATE_POLICY or workspace/update_policy.yaml, when it exists)"),
+		none:     fs.Bool("no-policy", false, "Do not check a policy; needed for -yes when no policy file exists"),
+		override: fs.String("override-policy", "", "Apply despite policy violations; the reason is written to the audit log"),
+	}
+}
+
+// check loads the policy and prints the violations of changes. It exits when -yes has no policy to rely on, and
+// on violations without -override-policy unless nothing is applied (dryRun).
+func (p policyFlags) check(changes []codesecurity.Change, yes, dryRun bool) []string {
+	path, required := codesecurity.PolicyPath(*p.path)
+	if *p.none && required {
+		log.Fatal("-policy and -no-policy cannot be combined")
+	}
+	var policy *codesecurity.Policy
+	if !*p.none {
+		var err error
+		if policy, err = codesecurity.LoadPolicy(path, required); err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+	}
+	if yes && !dryRun && policy == nil && !*p.none {
+		log.Fatalf("-yes needs a policy and none was found at %s. Pass -policy <file>, or -no-policy to apply without one", path)
+	}
+	violations := policy.Check(changes)
+	if len(violations) == 0 {
+		if policy != nil {
+			fmt.Printf("✅ Policy %s: no violations\n", path)
+		}
+		return nil
+	}
+	fmt.Printf("\n--- Policy violations (%s) ---\n", path)
+	for _, v := range violations {
+		fmt.Printf("🚫 %s\n", v)
+	}
+	if dryRun {
+		return violations
+	}
+	if strings.TrimSpace(*p.override) == "" {
+		fmt.Fprintln(os.Stderr, "Blocked by policy. Re-run with -override-policy \"<reason>\" to apply it anyway.")
+		os.Exit(1)
+	}
+	fmt.Printf("⚠️  Overriding the policy: %s\n", *p.override)
+	return violations
+}
+
+// confirm asks before anything is changed.
+func confirm() bool {
+	fmt.Print("Apply these changes? (y/N): ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer != "y\n" && answer != "Y\n" {
+		fmt.Println("Aborted.")
+		return false
+	}
+	return true
+}
+
+// apply makes the changes of a diff in the target. The configuration it replaces is saved to the history first,
+// and nothing is changed when that fails. Then the configuration is created (current is nil) or updated, and
+// default_for_new_repos is set. action names the change in the history and the audit log: "copy" or "revert".
+func apply(client *http.Client, target instance, current *codesecurity.Config, desired codesecurity.Config, changes []codesecurity.Change, historyDir, action string, audit func(auditlog.Entry)) {
+	var configChanged, defaultChanged bool
+	for _, c := range changes {
+		if c.Path[0] == "default_for_new_repos" {
+			defaultChanged = true
+		} else {
+			configChanged = true
+		}
+	}
+	if current != nil {
+		path, err := codesecurity.SaveHistory(historyDir, target.Org, *current, codesecurity.ChangedFields(changes), action)
+		if err != nil {
+			log.Fatalf("Failed to save the previous version to %s, nothing was changed: %v", historyDir, err)
+		}
+		fmt.Printf("📚 Previous version saved to %s\n", path)
+	}
+
+	body := codesecurity.Fields(desired)
+	configID := 0
+	if current == nil {
+		url := fmt.Sprintf("%s/orgs/%s/code-security/configurations", target.APIBase, target.Org)
+		status, respBody, err := ghapi.Send(client, "POST", url, target.Token, body)
+		entry := auditlog.Entry{Action: "create_configuration", Method: "POST", URL: url, Status: status, After: body}
+		if err != nil {
+			entry.Error = err.Error()
+			audit(entry)
+			log.Fatalf("Failed to create '%s' in %s: %v", desired.Name, target, err)
+		}
+		// GHEC returns the configuration, GHES wraps it in "value"
+		var created struct {
+			ID    int `json:"id"`
+			Value struct {
+				ID int `json:"id"`
+			} `json:"value"`
+		}
+		if err := json.Unmarshal(respBody, &created); err != nil {
+			log.Fatalf("Created '%s' in %s, but could not parse the response: %v", desired.Name, target, err)
+		}
+		configID = created.ID
+		if configID == 0 {
+			configID = created.Value.ID
+		}
+		entry.ConfigID, entry.After = configID, auditlog.Body(respBody)
+		audit(entry)
+		fmt.Printf("✅ Created '%s' in %s\n", desired.Name, target)
+	} else {
+		configID = current.ID
+		if configChanged {
+			url := fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d", target.APIBase, target.Org, configID)
+			status, respBody, err := ghapi.Send(client, "PATCH", url, target.Token, body)
+			entry := auditlog.Entry{Action: "update_configuration", Method: "PATCH", URL: url, ConfigID: configID, Status: status, Before: current}
+			if action == "revert" {
+				entry.Action = "revert_configuration"
+			}
+			if err != nil {
+				entry.After, entry.Error = body, err.Error()
+				audit(entry)
+				log.Fatalf("Failed to update '%s' in %s: %v", desired.Name, target, err)
+			}
+			entry.After = auditlog.Body(respBody)
+			audit(entry)
+			fmt.Printf("✅ Updated '%s' in %s\n", desired.Name, target)
+		}
+	}
+
+	if defaultChanged {
+		if configID == 0 {
+			log.Fatalf("Could not determine the ID of '%s' in %s to set default_for_new_repos", desired.Name, target)
+		}
+		url := fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/defaults", target.APIBase, target.Org, configID)
+		payload := map[string]string{"default_for_new_repos": desired.DefaultForNewRepos}
+		status, _, err := ghapi.Send(client, "PUT", url, target.Token, payload)
+		entry := auditlog.Entry{Action: "set_default_for_new_repos", Method: "PUT", URL: url, ConfigID: configID, Status: status, After: payload}
+		if current != nil && current.DefaultForNewRepos != "" {
+			entry.Before = map[string]string{"default_for_new_repos": current.DefaultForNewRepos}
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			audit(entry)
+			log.Fatalf("Failed to set default_for_new_repos in %s: %v", target, err)
+		}
+		audit(entry)
+		fmt.Printf("✅ default_for_new_repos set to %s\n", desired.DefaultForNewRepos)
+	}
+}
+
+func copyUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run config.go copy -from-org A -to-org B -config name [-to-config name] [-to-ghes-url URL] [-copy-default] [-policy file|-no-policy] [-dry-run] [-yes]")
+}
+
+func runCopy(args []string) {
+	fs := flag.NewFlagSet("copy", flag.ExitOnError)
+	fromOrg := fs.String("from-org", "", "Organization to read the configuration from")
+	toOrg := fs.String("to-org", "", "Organization to create or update the configuration in")
+	configName := fs.String("config", "", "Name of the configuration to copy")
+	toConfig := fs.String("to-config", "", "Name of the configuration in the target (default: same as -config)")
+	fromEndpoint := fs.String("from-endpoint", "", "GHEC or GHES for the source (default: GITHUB_ENDPOINT)")
+	toEndpoint := fs.String("to-endpoint", "", "GHEC or GHES for the target (default: GHES when -to-ghes-url is set, else GITHUB_ENDPOINT)")
+	fromGHESURL := fs.String("from-ghes-url", "", "GHES API URL of the source (default: GHES_URL)")
+	toGHESURL := fs.String("to-ghes-url", "", "GHES API URL of the target (default: GHES_URL)")
+	fromToken := fs.String("from-token", "", "Token for the source (default: GITHUB_TOKEN_ORG or GITHUB_TOKEN, when both sides are on the same server)")
+	toToken := fs.String("to-token", "", "Token for the target (default: GITHUB_TOKEN_ORG or GITHUB_TOKEN, when both sides are on the same server)")
+	copyDefault := fs.Bool("copy-default", false, "Also apply the source's default_for_new_repos in the target")
+	dryRun := fs.Bool("dry-run", false, "Show the diff and adaptations without changing the target")
+	yes := fs.Bool("yes", false, "Apply without asking for confirmation (needs a policy or -no-policy; policy violations still block)")
+	policy := addPolicyFlags(fs)
+	auditLog := fs.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	historyDir := fs.String("history-dir", "workspace/.history", "Directory the target configuration is saved to before it is overwritten")
+	colorMode := fs.String("color", "auto", "Color the diff: auto, always or never")
+	fs.Usage = func() {
+		copyUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *fromOrg == "" || *toOrg == "" || *configName == "" {
+		copyUsage()
+		os.Exit(2)
+	}
+	if *toConfig == "" {
+		*toConfig = *configName
+	}
+	source := resolveInstance(*fromOrg, *fromEndpoint, *fromGHESURL, *fromToken, "from")
+	target := resolveInstance(*toOrg, *toEndpoint, *toGHESURL, *toToken, "to")
+	if source.APIBase != target.APIBase && (*fromToken == "" || *toToken == "") {
+		// One token from the environment would be sent to both servers
+		log.Fatalf("Set -from-token and -to-token when the source (%s) and target (%s) are on different servers", source.APIBase, target.APIBase)
+	}
+	if source.APIBase == target.APIBase && strings.EqualFold(source.Org, target.Org) && *configName == *toConfig {
+		log.Fatal("Source and target are the same configuration")
+	}
+
+	client := &http.Client{}
+	var sourceConfigs, targetConfigs []codesecurity.Config
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", source.APIBase, source.Org), source.Token, &sourceConfigs); err != nil {
+		log.Fatalf("Failed to list configurations in %s: %v", source, err)
+	}
+	src := codesecurity.Find(sourceConfigs, *configName)
+	if src == nil {
+		log.Fatalf("Configuration '%s' not found in %s", *configName, source)
+	}
+	if src.TargetType == "global" {
+		log.Fatalf("'%s' is managed by GitHub and cannot be copied", *configName)
+	}
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", target.APIBase, target.Org), target.Token, &targetConfigs); err != nil {
+		log.Fatalf("Failed to list configurations in %s: %v", target, err)
+	}
+	current := codesecurity.Find(targetConfigs, *toConfig)
+
+	// Team IDs only need translating when the configuration has team reviewers
+	var sourceTeams, targetTeams map[int]string
+	if opts := src.SecretScanningDelegatedBypassOptions; opts != nil {
+		for _, r := range opts.Reviewers {
+			if r.ReviewerType != "TEAM" {
+				continue
+			}
+			var err error
+			if sourceTeams, err = teamSlugs(client, source); err != nil {
+				log.Fatalf("Failed to list teams in %s: %v", source, err)
+			}
+			if targetTeams, err = teamSlugs(client, target); err != nil {
+				log.Fatalf("Failed to list teams in %s: %v", target, err)
+			}
+			break
+		}
+	}
+	desired, notes := adaptConfig(*src, target, sourceTeams, targetTeams)
+	desired.Name = *toConfig
+
+	if *copyDefault {
+		var err error
+		if desired.DefaultForNewRepos, err = defaultFor(client, source, src.ID); err != nil {
+			log.Fatalf("Failed to read default configurations in %s: %v", source, err)
+		}
+		if current != nil {
+			if current.DefaultForNewRepos, err = defaultFor(client, target, current.ID); err != nil {
+				log.Fatalf("Failed to read default configurations in %s: %v", target, err)
+			}
+		}
+	}
+
+	fmt.Printf("Copying '%s' from %s to '%s' in %s\n", *configName, source, *toConfig, target)
+	if len(notes) > 0 {
+		fmt.Println("\n--- Adapted for the target ---")
+		for _, n := range notes {
+			fmt.Printf("⚠️  %s\n", n)
+		}
+	}
+	var have codesecurity.Config
+	if current != nil {
+		have = *current
+	}
+	changes := codesecurity.Diff(have, desired)
+	from := fmt.Sprintf("%s/%s", target.Org, *toConfig)
+	if current == nil {
+		from += " (does not exist yet)"
+	}
+	fmt.Println()
+	codesecurity.WriteDiff(os.Stdout, "text", changes, from, fmt.Sprintf("%s/%s", source.Org, *configName), codesecurity.UseColor(*colorMode))
+	if len(changes) == 0 {
+		return
+	}
+	violations := policy.check(changes, *yes, *dryRun)
+	if *dryRun {
+		fmt.Println("\nDry run, the target was not changed.")
+		return
+	}
+	if !*yes && !confirm() {
+		return
+	}
+
+	actor := auditlog.Actor(client, target.APIBase, target.Token)
+	apply(client, target, current, desired, changes, *historyDir, "copy", func(e auditlog.Entry) {
+		e.Actor, e.Command, e.Org, e.Config = actor, "config_copy", target.Org, *toConfig
+		if len(violations) > 0 {
+			e.PolicyViolations, e.OverrideReason = violations, strings.TrimSpace(*policy.override)
+		}
+		auditlog.Append(auditlog.Path(*auditLog), e)
+	})
+}
+
+func historyUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run config.go history -org org -config name [-history-dir dir]")
+}
+
+// runHistory lists the versions of a configuration saved before each update, copy or revert.
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	org := fs.String("org", "", "Organization of the configuration")
+	configName := fs.String("config", "", "Name of the configuration")
+	historyDir := fs.String("history-dir", "workspace/.history", "Directory the versions are saved in")
+	fs.Usage = func() {
+		historyUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *org == "" || *configName == "" {
+		historyUsage()
+		os.Exit(2)
+	}
+
+	versions, err := codesecurity.ListHistory(*historyDir, *org, *configName)
+	if err != nil {
+		log.Fatalf("Failed to read history: %v", err)
+	}
+	if len(versions) == 0 {
+		fmt.Printf("No saved versions of '%s' in '%s' under %s\n", *configName, *org, *historyDir)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSAVED\tFIELDS CHANGED")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Version, v.Saved, v.Changed)
+	}
+	w.Flush()
+	fmt.Printf("\nRevert with: go run config.go revert -org %s -config '%s' -to <version>\n", *org, *configName)
+}
+
+func revertUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run config.go revert -org org -config name -to <version>|latest [-policy file|-no-policy] [-dry-run] [-yes]")
+}
+
+// runRevert re-applies a saved version through the same diff, policy check and confirmation as an update.
+func runRevert(args []string) {
+	fs := flag.NewFlagSet("revert", flag.ExitOnError)
+	org := fs.String("org", "", "Organization of the configuration")
+	configName := fs.String("config", "", "Name of the configuration")
+	to := fs.String("to", "", "Version to re-apply: 'latest' or a version (or unique prefix) from config history")
+	endpoint := fs.String("endpoint", "", "GHEC or GHES (default: GHES when -ghes-url is set, else GITHUB_ENDPOINT)")
+	ghesURL := fs.String("ghes-url", "", "GHES API URL (default: GHES_URL)")
+	token := fs.String("token", "", "GitHub API token (default: GITHUB_TOKEN_ORG or GITHUB_TOKEN)")
+	dryRun := fs.Bool("dry-run", false, "Show the diff without changing the configuration")
+	yes := fs.Bool("yes", false, "Apply without asking for confirmation (needs a policy or -no-policy; policy violations still block)")
+	policy := addPolicyFlags(fs)
+	auditLog := fs.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	historyDir := fs.String("history-dir", "workspace/.history", "Directory the versions are saved in; the state the revert replaces is saved there too")
+	colorMode := fs.String("color", "auto", "Color the diff: auto, always or never")
+	fs.Usage = func() {
+		revertUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *org == "" || *configName == "" || *to == "" {
+		revertUsage()
+		os.Exit(2)
+	}
+
+	versions, err := codesecurity.ListHistory(*historyDir, *org, *configName)
+	if err != nil {
+		log.Fatalf("Failed to read history: %v", err)
+	}
+	v, err := codesecurity.FindVersion(versions, *to)
+	if err != nil {
+		log.Fatalf("Cannot revert '%s': %v (see config history)", *configName, err)
+	}
+	// Versions are saved resolved, so they are read as they are
+	data, err := ioutil.ReadFile(v.Path)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", v.Path, err)
+	}
+	var desired codesecurity.Config
+	if err := yaml.Unmarshal(data, &desired); err != nil {
+		log.Fatalf("Failed to parse %s: %v", v.Path, err)
+	}
+	if desired.Name != *configName {
+		log.Fatalf("Version file %s is for configuration '%s', not '%s'", v.Path, desired.Name, *configName)
+	}
+
+	target := resolveInstance(*org, *endpoint, *ghesURL, *token, "")
+	client := &http.Client{}
+	var configs []codesecurity.Config
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", target.APIBase, target.Org), target.Token, &configs); err != nil {
+		log.Fatalf("Failed to list configurations in %s: %v", target, err)
+	}
+	current := codesecurity.Find(configs, *configName)
+	var have codesecurity.Config
+	if current != nil {
+		if current.DefaultForNewRepos, err = defaultFor(client, target, current.ID); err != nil {
+			log.Fatalf("Failed to read default configurations in %s: %v", target, err)
+		}
+		have = *current
+	}
+
+	fmt.Printf("Reverting '%s' in %s to version %s (saved %s)\n\n", *configName, target, v.Version, v.Saved)
+	from := fmt.Sprintf("%s/%s on GitHub", target.Org, *configName)
+	if current == nil {
+		from += " (does not exist anymore)"
+	}
+	changes := codesecurity.Diff(have, desired)
+	codesecurity.WriteDiff(os.Stdout, "text", changes, from, v.Path, codesecurity.UseColor(*colorMode))
+	if len(changes) == 0 {
+		return
+	}
+	violations := policy.check(changes, *yes, *dryRun)
+	if *dryRun {
+		fmt.Println("\nDry run, the configuration was not changed.")
+		return
+	}
+	if !*yes && !confirm() {
+		return
+	}
+
+	actor := auditlog.Actor(client, target.APIBase, target.Token)
+	apply(client, target, current, desired, changes, *historyDir, "revert", func(e auditlog.Entry) {
+		e.Actor, e.Command, e.Org, e.Config = actor, "config_revert", target.Org, *configName
+		if len(violations) > 0 {
+			e.PolicyViolations, e.OverrideReason = violations, strings.TrimSpace(*policy.override)
+		}
+		auditlog.Append(auditlog.Path(*auditLog), e)
+	})
+}
+
+func renderUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run config.go render -yaml config.yaml [-org org] [-var NAME=value ...] [-output file]")
+}
+
+// runRender prints the effective configuration create_org_config.go and update_org_config.go would load.
+func runRender(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	yamlPath := fs.String("yaml", "", "Configuration YAML to render")
+	org := fs.String("org", "", "Organization, available as ${ORG}")
+	output := fs.String("output", "", "Write the effective YAML to this file instead of stdout")
+	vars := configyaml.Vars{}
+	fs.Var(vars, "var", "Variable for ${NAME} references as NAME=value (repeatable, overrides CFG_NAME in the environment)")
+	fs.Usage = func() {
+		renderUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *yamlPath == "" && fs.NArg() == 1 {
+		*yamlPath = fs.Arg(0)
+	}
+	if *yamlPath == "" {
+		renderUsage()
+		os.Exit(2)
+	}
+	if _, ok := vars["ORG"]; !ok && *org != "" {
+		vars["ORG"] = *org
+	}
+
+	data, err := configyaml.Load(*yamlPath, vars)
+	if err != nil {
+		log.Fatalf("Failed to render: %v", err)
+	}
+	// Fail here rather than in create/update when a substituted value has the wrong type
+	var cfg codesecurity.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		log.Fatalf("Rendered configuration is not valid: %v", err)
+	}
+	if cfg.Name == "" {
+		log.Fatalf("Rendered configuration has no name")
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := ioutil.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+	fmt.Printf("✅ Rendered '%s' to %s\n", cfg.Name, *output)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run config.go <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  copy     Copy a configuration to another organization, on GHEC or GHES")
+	fmt.Fprintln(os.Stderr, "  history  List the saved versions of a configuration")
+	fmt.Fprintln(os.Stderr, "  revert   Re-apply a saved version of a configuration")
+	fmt.Fprintln(os.Stderr, "  render   Print a configuration YAML with extends and ${VAR} references resolved")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "copy":
+		runCopy(os.Args[2:])
+	case "history":
+		runHistory(os.Args[2:])
+	case "revert":
+		runRevert(os.Args[2:])
+	case "render":
+		runRender(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -12,10 +12,13 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/configyaml"
 )
 
 // setDefaultForNewRepos sets the default code security configuration for new repositories in the org.
-func setDefaultForNewRepos(ghesURL, org, token string, configID int, defaultFor string) error {
+func setDefaultForNewRepos(ghesURL, org, token string, configID int, defaultFor string, audit func(auditlog.Entry)) error {
 	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
 	var url string
 	switch githubEndpoint {
@@ -40,17 +43,24 @@ func setDefaultForNewRepos(ghesURL, org, token string, configID int, defaultFor
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 	req.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
+	entry := auditlog.Entry{Action: "set_default_for_new_repos", Method: "PUT", URL: url, ConfigID: configID, After: reqBody}
 	resp, err := client.Do(req)
 	if err != nil {
+		entry.Error = err.Error()
+		audit(entry)
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 	respBody, _ := ioutil.ReadAll(resp.Body)
+	entry.Status = resp.StatusCode
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		audit(entry)
 		fmt.Println("Default for new repos set successfully:")
 		fmt.Println(string(respBody))
 		return nil
 	} else {
+		entry.Error = strings.TrimSpace(string(respBody))
+		audit(entry)
 		return fmt.Errorf("API error: %s\n%s", resp.Status, string(respBody))
 	}
 }
@@ -73,11 +83,12 @@ type CodeSecurityConfig struct {
 	Name                               string `yaml:"name" json:"name"`
 	Description                        string `yaml:"description" json:"description"`
 	AdvancedSecurity                   string `yaml:"advanced_security" json:"advanced_security"`
-	// DependencyGraph                    string `yaml:"dependency_graph" json:"dependency_graph"`
-	// DependencyGraphAutosubmitAction    string `yaml:"dependency_graph_autosubmit_action" json:"dependency_graph_autosubmit_action"`
-	// DependencyGraphAutosubmitActionOptions struct {
-	// 	LabeledRunners bool `yaml:"labeled_runners" json:"labeled_runners"`
-	// } `yaml:"dependency_graph_autosubmit_action_options" json:"dependency_graph_autosubmit_action_options"`
+	// Sent only when set, so files without these fields create the same configuration as before
+	DependencyGraph                    string `yaml:"dependency_graph" json:"dependency_graph,omitempty"`
+	DependencyGraphAutosubmitAction    string `yaml:"dependency_graph_autosubmit_action" json:"dependency_graph_autosubmit_action,omitempty"`
+	DependencyGraphAutosubmitActionOptions *struct {
+		LabeledRunners bool `yaml:"labeled_runners" json:"labeled_runners"`
+	} `yaml:"dependency_graph_autosubmit_action_options" json:"dependency_graph_autosubmit_action_options,omitempty"`
 	DependabotAlerts                   string `yaml:"dependabot_alerts" json:"dependabot_alerts"`
 	DependabotSecurityUpdates          string `yaml:"dependabot_security_updates" json:"dependabot_security_updates"`
 	CodeScanningDefaultSetup           string `yaml:"code_scanning_default_setup" json:"code_scanning_default_setup"`
@@ -89,7 +100,7 @@ type CodeSecurityConfig struct {
 	SecretScanningPushProtection       string `yaml:"secret_scanning_push_protection" json:"secret_scanning_push_protection"`
 	SecretScanningValidityChecks       string `yaml:"secret_scanning_validity_checks" json:"secret_scanning_validity_checks"`
 	SecretScanningNonProviderPatterns  string `yaml:"secret_scanning_non_provider_patterns" json:"secret_scanning_non_provider_patterns"`
-	// SecretScanningGenericSecrets       string `yaml:"secret_scanning_generic_secrets" json:"secret_scanning_generic_secrets"`
+	SecretScanningGenericSecrets       string `yaml:"secret_scanning_generic_secrets" json:"secret_scanning_generic_secrets,omitempty"`
 	SecretScanningDelegatedBypass      string `yaml:"secret_scanning_delegated_bypass" json:"secret_scanning_delegated_bypass"`
 	SecretScanningDelegatedBypassOptions struct {
 		Reviewers []struct {
@@ -97,8 +108,8 @@ type CodeSecurityConfig struct {
 			ReviewerType string `yaml:"reviewer_type" json:"reviewer_type"`
 		} `yaml:"reviewers" json:"reviewers"`
 	} `yaml:"secret_scanning_delegated_bypass_options" json:"secret_scanning_delegated_bypass_options"`
-	// SecretScanningDelegatedAlertDismissal string `yaml:"secret_scanning_delegated_alert_dismissal" json:"secret_scanning_delegated_alert_dismissal"`
-	// PrivateVulnerabilityReporting      string `yaml:"private_vulnerability_reporting" json:"private_vulnerability_reporting"`
+	SecretScanningDelegatedAlertDismissal string `yaml:"secret_scanning_delegated_alert_dismissal" json:"secret_scanning_delegated_alert_dismissal,omitempty"`
+	PrivateVulnerabilityReporting      string `yaml:"private_vulnerability_reporting" json:"private_vulnerability_reporting,omitempty"`
 	Enforcement                        string `yaml:"enforcement" json:"enforcement"`
 	// CodeScanningOptions struct {
 	//     AllowAdvanced bool `yaml:"allow_advanced" json:"allow_advanced"`
@@ -112,6 +123,9 @@ func main() {
 	token := flag.String("token", "", "GitHub API token")
 	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
 	ghesURL := flag.String("ghes-url", "", "Base URL for GHES api (ignored for GHEC)")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	vars := configyaml.Vars{}
+	flag.Var(vars, "var", "Variable for ${NAME} references in the YAML as NAME=value (repeatable, ${ORG} defaults to -org)")
 	flag.Parse()
 
 	// GHES_URL env var fallback
@@ -135,7 +149,10 @@ func main() {
 		log.Fatal("Usage: go run create_org_config.go -yaml config.yaml -token <redacted> -org <org>")
 	}
 
-	data, err := ioutil.ReadFile(*yamlPath)
+	if _, ok := vars["ORG"]; !ok {
+		vars["ORG"] = *org
+	}
+	data, err := configyaml.Load(*yamlPath, vars)
 	if err != nil {
 		log.Fatalf("Failed to read YAML file: %v", err)
 	}
@@ -152,12 +169,14 @@ func main() {
 	}
 
 	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
-	var url string
+	var url, apiBase string
 	switch githubEndpoint {
 	case "GHEC":
+		apiBase = "https://api.github.com"
 		url = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations", *org)
 	case "GHES":
 		if *ghesURL == "" { log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES") }
+		apiBase = *ghesURL
 		url = fmt.Sprintf("%s/orgs/%s/code-security/configurations", *ghesURL, *org)
 	default:
 		log.Fatalf("GITHUB_ENDPOINT environment variable must be set to either GHEC or GHES, or left unset for GHES as default")
@@ -174,14 +193,22 @@ func main() {
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	actor := auditlog.Actor(client, apiBase, githubToken)
+	audit := func(e auditlog.Entry) {
+		e.Actor, e.Command, e.Org, e.Config = actor, "create_org_config", *org, config.Name
+		auditlog.Append(auditlog.Path(*auditLog), e)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
+		audit(auditlog.Entry{Action: "create_configuration", Method: "POST", URL: url, After: config, Error: err.Error()})
 		log.Fatalf("Request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	   body, _ := ioutil.ReadAll(resp.Body)
+	   entry := auditlog.Entry{Action: "create_configuration", Method: "POST", URL: url, Status: resp.StatusCode, After: auditlog.Body(body)}
 	   if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		   audit(entry)
 		   fmt.Println("Configuration created successfully:")
 		   fmt.Println(string(body))
 
@@ -205,7 +232,7 @@ func main() {
 					   }
 				   }
 				   if configID != 0 {
-					   err := setDefaultForNewRepos(*ghesURL, *org, githubToken, configID, tlcFile.DefaultForNewRepos)
+					   err := setDefaultForNewRepos(*ghesURL, *org, githubToken, configID, tlcFile.DefaultForNewRepos, audit)
 					   if err != nil {
 						   fmt.Fprintf(os.Stderr, "Failed to set default for new repos: %v\n", err)
 					   }
@@ -215,6 +242,8 @@ func main() {
 			   }
 		   }
 	   } else {
+		   entry.After, entry.Error = config, strings.TrimSpace(string(body))
+		   audit(entry)
 		   fmt.Fprintf(os.Stderr, "API error: %s\n%s\n", resp.Status, string(body))
 		   os.Exit(1)
 	   }
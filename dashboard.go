@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is the subset of the metrics.go snapshot rendered in the dashboard.
+type Snapshot struct {
+	Organization string    `json:"organization"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	TotalRepos   int       `json:"total_repos"`
+	Features     map[string]struct {
+		Enabled int     `json:"enabled"`
+		Percent float64 `json:"percent"`
+	} `json:"features"`
+	ConfigAttachments []struct {
+		Name     string         `json:"name"`
+		Attached int            `json:"attached"`
+		ByStatus map[string]int `json:"by_status"`
+	} `json:"config_attachments"`
+	OpenAlerts map[string]struct {
+		Total      int            `json:"total"`
+		BySeverity map[string]int `json:"by_severity"`
+	} `json:"open_alerts"`
+	MTTR map[string]struct {
+		Remediated int     `json:"remediated"`
+		MeanDays   float64 `json:"mean_days"`
+	} `json:"mttr"`
+	MTTRWindowDays int `json:"mttr_window_days"`
+	Repositories   []struct {
+		Name                string          `json:"name"`
+		Features            map[string]bool `json:"features"`
+		OpenAlerts          map[string]int  `json:"open_alerts"`
+		Configuration       string          `json:"configuration"`
+		ConfigurationStatus string          `json:"configuration_status"`
+	} `json:"repositories"`
+}
+
+var featureColumns = []struct{ Key, Label string }{
+	{"advanced_security", "Advanced Security"},
+	{"secret_scanning", "Secret Scanning"},
+	{"secret_scanning_push_protection", "Push Protection"},
+	{"dependabot_security_updates", "Dependabot Updates"},
+	{"code_scanning_default_setup", "Code Scanning Default Setup"},
+}
+
+var alertTypes = []struct{ Key, Label string }{
+	{"code_scanning", "Code Scanning"},
+	{"secret_scanning", "Secret Scanning"},
+	{"dependabot", "Dependabot"},
+}
+
+// Template data, flattened so the template stays free of map lookups.
+type featureCard struct {
+	Label   string
+	Enabled int
+	Percent float64
+}
+
+type alertRow struct {
+	Label      string
+	Total      int
+	Severities string
+	MTTRDays   float64
+	Remediated int
+}
+
+type configRow struct {
+	Name     string
+	Attached int
+	Other    string
+}
+
+type cell struct {
+	Value string
+	Sort  string
+	Class string
+}
+
+type repoRow struct {
+	Name          string
+	Configuration string
+	Missing       string
+	Cells         []cell
+}
+
+type dashboardData struct {
+	Organization   string
+	GeneratedAt    string
+	TotalRepos     int
+	MTTRWindowDays int
+	Features       []featureCard
+	Alerts         []alertRow
+	Configs        []configRow
+	ConfigNames    []string
+	FeatureLabels  []string
+	AlertLabels    []string
+	Repos          []repoRow
+}
+
+// latestFromStore returns the newest snapshot for org in the JSONL history store.
+func latestFromStore(path, org string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var latest []byte
+	var latestAt time.Time
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var head struct {
+			Organization string    `json:"organization"`
+			GeneratedAt  time.Time `json:"generated_at"`
+		}
+		if json.Unmarshal(line, &head) != nil || !strings.EqualFold(head.Organization, org) {
+			continue
+		}
+		if latest == nil || head.GeneratedAt.After(latestAt) {
+			latest = append([]byte(nil), line...)
+			latestAt = head.GeneratedAt
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no snapshots for '%s'", org)
+	}
+	return latest, nil
+}
+
+// formatCounts renders a map as "key: n" pairs sorted by key.
+func formatCounts(m map[string]int, skip string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != skip {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, m[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func buildData(s Snapshot) dashboardData {
+	d := dashboardData{
+		Organization:   s.Organization,
+		GeneratedAt:    s.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		TotalRepos:     s.TotalRepos,
+		MTTRWindowDays: s.MTTRWindowDays,
+	}
+	var collected []struct{ Key, Label string }
+	for _, f := range featureColumns {
+		m, ok := s.Features[f.Key]
+		if !ok {
+			continue
+		}
+		collected = append(collected, f)
+		d.Features = append(d.Features, featureCard{Label: f.Label, Enabled: m.Enabled, Percent: m.Percent})
+		d.FeatureLabels = append(d.FeatureLabels, f.Label)
+	}
+	for _, a := range alertTypes {
+		counts, ok := s.OpenAlerts[a.Key]
+		if !ok {
+			continue
+		}
+		d.Alerts = append(d.Alerts, alertRow{
+			Label:      a.Label,
+			Total:      counts.Total,
+			Severities: formatCounts(counts.BySeverity, ""),
+			MTTRDays:   s.MTTR[a.Key].MeanDays,
+			Remediated: s.MTTR[a.Key].Remediated,
+		})
+		d.AlertLabels = append(d.AlertLabels, a.Label)
+	}
+	for _, c := range s.ConfigAttachments {
+		d.Configs = append(d.Configs, configRow{Name: c.Name, Attached: c.Attached, Other: formatCounts(c.ByStatus, "attached")})
+		d.ConfigNames = append(d.ConfigNames, c.Name)
+	}
+	for _, r := range s.Repositories {
+		row := repoRow{Name: r.Name, Configuration: r.Configuration}
+		if r.ConfigurationStatus != "" && r.ConfigurationStatus != "attached" {
+			row.Configuration += " (" + r.ConfigurationStatus + ")"
+		}
+		var missing []string
+		for _, f := range collected {
+			if r.Features[f.Key] {
+				row.Cells = append(row.Cells, cell{Value: "✅", Sort: "1", Class: "on"})
+			} else {
+				row.Cells = append(row.Cells, cell{Value: "❌", Sort: "0", Class: "off"})
+				missing = append(missing, f.Label)
+			}
+		}
+		row.Missing = strings.Join(missing, "|")
+		for _, a := range alertTypes {
+			if _, ok := s.OpenAlerts[a.Key]; !ok {
+				continue
+			}
+			n := r.OpenAlerts[a.Key]
+			class := ""
+			if n > 0 {
+				class = "warn"
+			}
+			row.Cells = append(row.Cells, cell{Value: fmt.Sprint(n), Sort: fmt.Sprint(n), Class: class})
+		}
+		d.Repos = append(d.Repos, row)
+	}
+	return d
+}
+
+const dashboardTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Security posture: {{.Organization}}</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+  h1 { margin-bottom: 0.2rem; }
+  .meta { color: #59636e; margin-bottom: 1.5rem; }
+  .cards { display: flex; flex-wrap: wrap; gap: 1rem; margin-bottom: 2rem; }
+  .card { border: 1px solid #d1d9e0; border-radius: 6px; padding: 0.8rem 1rem; min-width: 11rem; }
+  .card .pct { font-size: 1.8rem; font-weight: 600; }
+  .bar { background: #eff2f5; height: 6px; border-radius: 3px; margin-top: 0.4rem; }
+  .bar span { display: block; height: 6px; border-radius: 3px; background: #1f883d; }
+  table { border-collapse: collapse; margin-bottom: 2rem; width: 100%; }
+  th, td { border: 1px solid #d1d9e0; padding: 0.35rem 0.6rem; text-align: left; }
+  th { background: #f6f8fa; cursor: pointer; user-select: none; white-space: nowrap; }
+  th.asc::after { content: " ▲"; }
+  th.desc::after { content: " ▼"; }
+  td.on, td.off { text-align: center; }
+  td.off { background: #ffebe9; }
+  td.warn { background: #fff8c5; font-weight: 600; }
+  .filters { display: flex; flex-wrap: wrap; gap: 1rem; margin-bottom: 0.8rem; align-items: center; }
+  .filters input, .filters select { padding: 0.3rem; }
+</style>
+</head>
+<body>
+<h1>Security posture: {{.Organization}}</h1>
+<div class="meta">Snapshot {{.GeneratedAt}} · {{.TotalRepos}} repositories</div>
+
+<h2>Feature adoption</h2>
+<div class="cards">
+{{- range .Features}}
+  <div class="card">
+    <div>{{.Label}}</div>
+    <div class="pct">{{printf "%.1f" .Percent}}%</div>
+    <div>{{.Enabled}} repositories</div>
+    <div class="bar"><span style="width: {{printf "%.1f" .Percent}}%"></span></div>
+  </div>
+{{- end}}
+</div>
+
+<h2>Open alerts</h2>
+<table class="sortable">
+  <thead><tr><th>Type</th><th>Open</th><th>By severity</th><th>MTTR (days, last {{.MTTRWindowDays}}d)</th><th>Remediated</th></tr></thead>
+  <tbody>
+  {{- range .Alerts}}
+    <tr><td>{{.Label}}</td><td data-sort="{{.Total}}">{{.Total}}</td><td>{{.Severities}}</td><td data-sort="{{.MTTRDays}}">{{printf "%.1f" .MTTRDays}}</td><td data-sort="{{.Remediated}}">{{.Remediated}}</td></tr>
+  {{- end}}
+  </tbody>
+</table>
+
+<h2>Configuration attachments</h2>
+<table class="sortable">
+  <thead><tr><th>Configuration</th><th>Attached</th><th>Other statuses</th></tr></thead>
+  <tbody>
+  {{- range .Configs}}
+    <tr><td>{{.Name}}</td><td data-sort="{{.Attached}}">{{.Attached}}</td><td>{{.Other}}</td></tr>
+  {{- end}}
+  </tbody>
+</table>
+
+<h2>Repositories</h2>
+<div class="filters">
+  <input id="search" type="search" placeholder="Filter by name">
+  <label>Missing feature
+    <select id="missing">
+      <option value="">(any)</option>
+      {{- range .FeatureLabels}}
+      <option>{{.}}</option>
+      {{- end}}
+    </select>
+  </label>
+  <label>Configuration
+    <select id="config">
+      <option value="">(any)</option>
+      <option value="-">(none)</option>
+      {{- range .ConfigNames}}
+      <option>{{.}}</option>
+      {{- end}}
+    </select>
+  </label>
+  <label><input id="alerts" type="checkbox"> With open alerts</label>
+  <span id="count"></span>
+</div>
+<table id="repos" class="sortable">
+  <thead><tr><th>Repository</th><th>Configuration</th>
+  {{- range .FeatureLabels}}<th>{{.}}</th>{{end}}
+  {{- range .AlertLabels}}<th>{{.}} alerts</th>{{end}}</tr></thead>
+  <tbody>
+  {{- range .Repos}}
+    <tr data-missing="{{.Missing}}" data-config="{{.Configuration}}"><td>{{.Name}}</td><td>{{.Configuration}}</td>
+    {{- range .Cells}}<td class="{{.Class}}" data-sort="{{.Sort}}">{{.Value}}</td>{{end}}</tr>
+  {{- end}}
+  </tbody>
+</table>
+
+<script>
+  document.querySelectorAll("table.sortable th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var table = th.closest("table");
+      var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+      var asc = !th.classList.contains("asc");
+      th.parentNode.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[index], y = b.cells[index];
+        var xv = x.dataset.sort !== undefined ? x.dataset.sort : x.textContent;
+        var yv = y.dataset.sort !== undefined ? y.dataset.sort : y.textContent;
+        var xn = parseFloat(xv), yn = parseFloat(yv);
+        var cmp = (!isNaN(xn) && !isNaN(yn)) ? xn - yn : xv.localeCompare(yv);
+        return asc ? cmp : -cmp;
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+
+  function applyFilters() {
+    var search = document.getElementById("search").value.toLowerCase();
+    var missing = document.getElementById("missing").value;
+    var config = document.getElementById("config").value;
+    var alerts = document.getElementById("alerts").checked;
+    var shown = 0, rows = document.querySelectorAll("#repos tbody tr");
+    rows.forEach(function (r) {
+      var ok = r.cells[0].textContent.toLowerCase().indexOf(search) !== -1;
+      if (ok && missing) { ok = r.dataset.missing.split("|").indexOf(missing) !== -1; }
+      if (ok && config === "-") { ok = r.dataset.config === ""; }
+      else if (ok && config) { ok = r.dataset.config.split(" (")[0] === config; }
+      if (ok && alerts) { ok = r.querySelector("td.warn") !== null; }
+      r.style.display = ok ? "" : "none";
+      if (ok) { shown++; }
+    });
+    document.getElementById("count").textContent = shown + " of " + rows.length + " repositories";
+  }
+  ["search", "missing", "config", "alerts"].forEach(function (id) {
+    document.getElementById(id).addEventListener("input", applyFilters);
+    document.getElementById(id).addEventListener("change", applyFilters);
+  });
+  applyFilters();
+</script>
+</body>
+</html>
+`
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	input := flag.String("input", "", "Metrics snapshot JSON written by metrics.go (default: latest snapshot for -org in -store)")
+	storePath := flag.String("store", "workspace/metrics-history.jsonl", "History store used when -input is not set")
+	output := flag.String("output", "", "HTML file to write (default workspace/<org>-dashboard.html)")
+	flag.Parse()
+
+	if *org == "" && *input == "" {
+		log.Fatal("Usage: go run dashboard.go -org <org> [-input workspace/<org>-metrics-<date>.json] [-output file.html]")
+	}
+
+	var data []byte
+	var err error
+	if *input != "" {
+		data, err = ioutil.ReadFile(*input)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *input, err)
+		}
+	} else {
+		data, err = latestFromStore(*storePath, *org)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v (run metrics.go first)", *storePath, err)
+		}
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Fatalf("Failed to parse snapshot JSON: %v", err)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("workspace/%s-dashboard.html", snapshot.Organization)
+	}
+
+	tmpl, err := template.New("dashboard").Parse(dashboardTemplate)
+	if err != nil {
+		log.Fatalf("Failed to parse template: %v", err)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *output, err)
+	}
+	defer f.Close()
+	if err := tmpl.Execute(f, buildData(snapshot)); err != nil {
+		log.Fatalf("Failed to render dashboard: %v", err)
+	}
+	fmt.Printf("✅ Wrote dashboard for '%s' (%d repositories) to %s\n", snapshot.Organization, snapshot.TotalRepos, *output)
+}
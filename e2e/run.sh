@@ -0,0 +1,365 @@
+#!/usr/bin/env bash
+# End-to-end checks: builds every command, starts fake_github.go with e2e/seed.json
+# and runs the commands against it. Needs go and curl, no network access.
+#
+#   ./e2e/run.sh              # all checks
+#   E2E_PORT=19000 ./e2e/run.sh
+
+set -u
+cd "$(dirname "$0")/.."
+
+PORT="${E2E_PORT:-18765}"
+URL="http://127.0.0.1:${PORT}"
+WORK="$(mktemp -d)"
+BIN="$WORK/bin"
+OUT="$WORK/workspace"
+mkdir -p "$BIN" "$OUT"
+
+export GITHUB_ENDPOINT=GHES
+export GHES_URL="$URL"
+export GITHUB_TOKEN=e2e-token
+export GITHUB_TOKEN_ORG=e2e-token
+export AUDIT_LOG="$OUT/audit.jsonl"
+
+PASSED=0
+FAILED=0
+FAKE_PID=""
+
+cleanup() {
+	if [ -n "$FAKE_PID" ]; then
+		kill "$FAKE_PID" 2>/dev/null
+	fi
+	if [ "$FAILED" -eq 0 ]; then
+		rm -rf "$WORK"
+	else
+		echo "Logs kept in $WORK"
+	fi
+}
+trap cleanup EXIT
+
+pass() { PASSED=$((PASSED + 1)); echo "✅ $1"; }
+fail() { FAILED=$((FAILED + 1)); echo "❌ $1"; [ -f "$WORK/$2.log" ] && sed 's/^/     /' "$WORK/$2.log" | tail -n 15; }
+
+# run NAME CMD... runs a command with its output in $WORK/NAME.log
+run() {
+	local name="$1"
+	shift
+	"$@" >"$WORK/$name.log" 2>&1 </dev/null
+}
+
+# expect_ok NAME PATTERN CMD... requires exit 0 and PATTERN in the output
+expect_ok() {
+	local name="$1" pattern="$2"
+	shift 2
+	if run "$name" "$@" && grep -Eq -- "$pattern" "$WORK/$name.log"; then
+		pass "$name"
+	else
+		fail "$name (expected success and /$pattern/)" "$name"
+	fi
+}
+
+# expect_fail NAME PATTERN CMD... requires a non-zero exit and PATTERN in the output
+expect_fail() {
+	local name="$1" pattern="$2"
+	shift 2
+	if ! run "$name" "$@" && grep -Eq -- "$pattern" "$WORK/$name.log"; then
+		pass "$name"
+	else
+		fail "$name (expected failure and /$pattern/)" "$name"
+	fi
+}
+
+# expect_file NAME FILE PATTERN requires PATTERN in FILE
+expect_file() {
+	if grep -Eq -- "$3" "$2" 2>/dev/null; then
+		pass "$1"
+	else
+		fail "$1 (expected /$3/ in $(basename "$2"))" "$1"
+	fi
+}
+
+# expect_state NAME PATTERN [ORG] requires PATTERN in the fake server state of ORG (default acme)
+expect_state() {
+	curl -s "$URL/_fake/state/${3:-acme}" | tr -d ' \n' >"$WORK/$1.log"
+	if grep -Eq -- "$2" "$WORK/$1.log"; then
+		pass "$1"
+	else
+		fail "$1 (expected /$2/ in server state)" "$1"
+	fi
+}
+
+faults() { curl -s -X PUT -d "$1" "$URL/_fake/faults" >/dev/null; }
+reset() { curl -s -X POST "$URL/_fake/reset" >/dev/null; }
+
+echo "Building commands..."
+for src in fake_github get_org_repos advanced_filter add_repo_to_config create_org_config update_org_config organization-check repo_security_settings \
+	code_scanning_default_setup export_secret_scanning_alerts triage_secret_alerts push_protection_bypass_report export_org_configs config \
+	export_dependabot_alerts export_code_scanning_alerts metrics trend dashboard serve http_fixtures audit rollback; do
+	if ! go build -o "$BIN/$src" "$src.go"; then
+		echo "❌ build $src.go"
+		FAILED=$((FAILED + 1))
+	fi
+done
+[ "$FAILED" -eq 0 ] || exit 1
+
+# A page size of 2 makes every list endpoint paginate
+"$BIN/fake_github" -addr "127.0.0.1:${PORT}" -seed e2e/seed.json -token e2e-token -max-per-page 2 >"$WORK/fake_github.log" 2>&1 &
+FAKE_PID=$!
+for _ in $(seq 1 50); do
+	curl -s "$URL/_fake/faults" >/dev/null && break
+	sleep 0.1
+done
+
+echo "Running checks against $URL"
+
+# Repository listing, filters and pagination
+expect_ok get_org_repos "Wrote 5 repositories" "$BIN/get_org_repos" -org acme -output "$OUT/repos.yaml"
+expect_file get_org_repos_all_pages "$OUT/repos.yaml" "api-fork"
+expect_ok get_org_repos_filtered "Wrote 2 repositories" "$BIN/get_org_repos" -org acme -archived exclude -templates exclude -language Go -output "$OUT/go.yaml"
+expect_file get_org_repos_filter_language "$OUT/go.yaml" "api"
+if grep -Eq "legacy|service-template" "$OUT/go.yaml"; then fail "get_org_repos_filter_excludes" get_org_repos_filtered; else pass "get_org_repos_filter_excludes"; fi
+# The inventory covers the whole organization, so a filtered run reports no repositories added or removed
+expect_ok repo_inventory_initial "5 added, 0 removed" "$BIN/get_org_repos" -org acme -output "$OUT/inv-repos.yaml" -inventory "$OUT/inventory.yaml"
+expect_ok repo_inventory_filtered "0 added, 0 removed, 0 renamed, 0 visibility changed" "$BIN/get_org_repos" -org acme -archived exclude -language Go -output "$OUT/inv-go.yaml" -inventory "$OUT/inventory.yaml" -changes "$OUT/inventory-changes.yaml"
+expect_ok repo_inventory_type "0 added, 0 removed" "$BIN/get_org_repos" -org acme -type sources -output "$OUT/inv-sources.yaml" -inventory "$OUT/inventory.yaml"
+if grep -q "api-fork" "$OUT/inv-sources.yaml"; then fail "repo_inventory_type_filter" repo_inventory_type; else pass "repo_inventory_type_filter"; fi
+# Repositories the filters exclude are not written to -added-output
+expect_ok repo_inventory_added_filtered "Wrote 3 added repositories .*\(2 filtered out\)" "$BIN/get_org_repos" -org acme -type sources -archived exclude \
+	-output "$OUT/inv-added.yaml" -inventory "$OUT/inventory-added.yaml" -added-output "$OUT/added-repos.txt"
+if grep -Eq "api-fork|legacy" "$OUT/added-repos.txt"; then fail "repo_inventory_added_excludes" repo_inventory_added_filtered; else pass "repo_inventory_added_excludes"; fi
+
+# Custom property filter, repository by repository and through the organization endpoint
+expect_ok advanced_filter "^api$" "$BIN/advanced_filter" -org acme -property tier -value 1 -outFile "$OUT/prod.txt"
+expect_file advanced_filter_output "$OUT/prod.txt" '^api$'
+expect_ok advanced_filter_org_endpoint "^api$" "$BIN/advanced_filter" -org acme -property tier -value 1 -fallback=false -outFile "$OUT/prod-org.txt"
+expect_file advanced_filter_org_endpoint_output "$OUT/prod-org.txt" '^api$'
+expect_ok advanced_filter_public_prod "Wrote 1 public production repos" "$BIN/advanced_filter" -org acme -property team -values frontend,mobile \
+	-outFile "$OUT/frontend.txt" -public-prod-outFile "$OUT/public-prod.txt"
+expect_file advanced_filter_public_prod_output "$OUT/public-prod.txt" '^web$'
+
+# Fault injection: server errors and rate limits must fail the command, not produce partial output
+faults '[{"method": "GET", "path": "/orgs/*/repos", "status": 502, "times": 1}]'
+expect_fail get_org_repos_5xx "502" "$BIN/get_org_repos" -org acme -output "$OUT/repos-5xx.yaml"
+faults '[{"method": "GET", "path": "/orgs/*/repos", "rate_limit": true, "after": 1}]'
+expect_fail get_org_repos_rate_limited "rate limit" "$BIN/get_org_repos" -org acme -output "$OUT/repos-rl.yaml"
+faults '[]'
+expect_fail bad_token "401|Bad credentials" env GITHUB_TOKEN_ORG=wrong "$BIN/get_org_repos" -org acme -token wrong -output "$OUT/repos-bad.yaml"
+
+# Configurations and attachments
+expect_ok create_org_config "created successfully" "$BIN/create_org_config" -org acme -yaml template/sample_org_config.yaml
+expect_state create_org_config_state '"TLC_standard":"all"'
+expect_ok config_render 'runner_label: "code-scanning-e2e"' "$BIN/config" render -yaml template/env_config.yaml -org acme -var ENV=e2e
+expect_fail config_render_undefined "undefined variable\(s\) ORG" "$BIN/config" render -yaml template/env_config.yaml
+expect_ok config_render_env 'runner_label: "code-scanning-staging"' env CFG_ENV=staging "$BIN/config" render -yaml template/env_config.yaml -org acme
+printf 'name: leak\ndescription: "${GITHUB_TOKEN}"\n' >"$OUT/token_config.yaml"
+expect_fail config_render_no_token "undefined variable\(s\) GITHUB_TOKEN" "$BIN/config" render -yaml "$OUT/token_config.yaml"
+expect_ok create_org_config_extends "created successfully" "$BIN/create_org_config" -org acme -yaml template/env_config.yaml -var ENV=e2e
+expect_state create_org_config_extends_state '"name":"acme-e2e".*"secret_scanning_push_protection":"enabled"'
+expect_ok add_repo_to_config "attached" "$BIN/add_repo_to_config" -org acme -config TLC_standard -repo web,api -id-cache "$OUT/id-cache.json" -snapshot "$OUT/attach-snapshot.json"
+expect_state add_repo_to_config_state '"web":"TLC_standard"'
+expect_file add_repo_to_config_snapshot "$OUT/attach-snapshot.json" '"configuration": "baseline"'
+sed 's/^description: .*/description: "Updated by e2e"/' template/sample_org_config.yaml >"$OUT/updated_config.yaml"
+expect_ok update_diff_text "^- description: TLC Standard Security Configuration$" "$BIN/update_org_config" -org acme -yaml "$OUT/updated_config.yaml" -diff-only
+expect_ok update_diff_json '"op": "replace",\s*$' "$BIN/update_org_config" -org acme -yaml "$OUT/updated_config.yaml" -diff-only -diff-format json
+expect_ok update_diff_markdown "written to" "$BIN/update_org_config" -org acme -yaml "$OUT/updated_config.yaml" -diff-only -diff-format markdown -diff-output "$OUT/diff.md"
+expect_file update_diff_markdown_table "$OUT/diff.md" '^\| `description` \| ✏️ changed \| `TLC Standard Security Configuration` \| `Updated by e2e` \|$'
+printf 'name: TLC_standard\ndescription: "Partial by e2e"\n' >"$OUT/partial_config.yaml"
+expect_ok update_diff_partial "^1 field\(s\) changed\.$" "$BIN/update_org_config" -org acme -yaml "$OUT/partial_config.yaml" -diff-only -diff-format markdown
+expect_ok update_org_config "Previous version saved" sh -c "echo y | '$BIN/update_org_config' -org acme -yaml '$OUT/updated_config.yaml' -history-dir '$OUT/history'"
+expect_state update_org_config_state '"description":"Updatedbye2e"'
+expect_fail update_history_unwritable "Failed to save the previous version" "$BIN/update_org_config" -org acme -yaml "$OUT/partial_config.yaml" \
+	-no-policy -yes -history-dir /dev/null/history
+expect_state update_history_unwritable_state '"description":"Updatedbye2e"'
+expect_ok config_history "Z +.*description" "$BIN/config" history -org acme -config TLC_standard -history-dir "$OUT/history"
+expect_ok config_history_org "No saved versions" "$BIN/config" history -org globex -config TLC_standard -history-dir "$OUT/history"
+expect_ok config_revert "^\+ description: TLC Standard Security Configuration$" sh -c "echo y | '$BIN/config' revert -org acme -config TLC_standard -to latest -history-dir '$OUT/history'"
+expect_state config_revert_state '"description":"TLCStandardSecurityConfiguration"'
+expect_fail config_revert_unknown "not found" "$BIN/config" revert -org acme -config TLC_standard -to 1999 -history-dir "$OUT/history"
+# A second save within the same second is <ts>-2 and is the later version
+mkdir -p "$OUT/history-order/acme/TLC_standard"
+printf 'name: TLC_standard\ndescription: first\n' >"$OUT/history-order/acme/TLC_standard/20200101-000000.yaml"
+printf 'name: TLC_standard\ndescription: second\n' >"$OUT/history-order/acme/TLC_standard/20200101-000000-2.yaml"
+expect_ok config_revert_same_second "^\+ description: second$" "$BIN/config" revert -org acme -config TLC_standard -to latest -dry-run -history-dir "$OUT/history-order"
+sed -e 's/^secret_scanning_push_protection: .*/secret_scanning_push_protection: "disabled"/' -e 's/^enforcement: .*/enforcement: "unenforced"/' \
+	template/sample_org_config.yaml >"$OUT/weakened_config.yaml"
+expect_ok update_diff_policy "secret_scanning_push_protection: enabled -> disabled \(may not be set to disabled\)" \
+	"$BIN/update_org_config" -org acme -yaml "$OUT/weakened_config.yaml" -policy template/update_policy.yaml -diff-only
+expect_fail update_policy_blocked "secret_scanning_push_protection: enabled -> disabled \(may not be set to disabled\)" \
+	"$BIN/update_org_config" -org acme -yaml "$OUT/weakened_config.yaml" -policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+expect_fail update_policy_locked "enforcement: enforced -> unenforced \(may not change once enforced\)" \
+	"$BIN/update_org_config" -org acme -yaml "$OUT/weakened_config.yaml" -policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+expect_ok update_policy_override "updated successfully" "$BIN/update_org_config" -org acme -yaml "$OUT/weakened_config.yaml" \
+	-policy template/update_policy.yaml -override-policy "e2e incident drill" -yes -history-dir "$OUT/history"
+expect_ok update_policy_revert "no violations" "$BIN/config" revert -org acme -config TLC_standard -to latest \
+	-policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+sed 's/^default_for_new_repos: .*/default_for_new_repos: private_and_internal/' template/sample_org_config.yaml >"$OUT/default_config.yaml"
+expect_ok update_default_diff "^\+ default_for_new_repos: private_and_internal$" "$BIN/update_org_config" -org acme -yaml "$OUT/default_config.yaml" -diff-only
+expect_fail update_yes_no_policy "-yes needs a policy" "$BIN/update_org_config" -org acme -yaml "$OUT/default_config.yaml" -yes -history-dir "$OUT/history"
+expect_ok update_default "default_for_new_repos set to private_and_internal" "$BIN/update_org_config" -org acme -yaml "$OUT/default_config.yaml" \
+	-policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+expect_state update_default_state '"TLC_standard":"private_and_internal"'
+expect_ok update_default_revert "default_for_new_repos set to all" "$BIN/config" revert -org acme -config TLC_standard -to latest -no-policy -yes -history-dir "$OUT/history"
+expect_ok export_org_configs "Exported [0-9]+ configuration" "$BIN/export_org_configs" -org acme -out "$OUT/configs"
+expect_file export_org_configs_default "$OUT/configs/TLC_standard.yaml" '^default_for_new_repos: all'
+expect_ok export_org_configs_roundtrip "No changes detected" "$BIN/update_org_config" -org acme -yaml "$OUT/configs/TLC_standard.yaml" -history-dir "$OUT/history"
+sed 's/^default_for_new_repos: .*/default_for_new_repos: public/' "$OUT/configs/TLC_standard.yaml" >"$OUT/exported_default.yaml"
+expect_ok export_org_configs_roundtrip_default "default_for_new_repos set to public" "$BIN/update_org_config" -org acme -yaml "$OUT/exported_default.yaml" -no-policy -yes -history-dir "$OUT/history"
+expect_state export_org_configs_roundtrip_default_state '"TLC_standard":"public"'
+expect_ok export_org_configs_exists "already exists" "$BIN/export_org_configs" -org acme -out "$OUT/configs" -config 'tlc_*'
+cat >"$OUT/dollar_config.yaml" <<'EOF'
+name: e2e-dollar
+description: "Costs $$5 per ${SEAT}"
+secret_scanning: enabled
+secret_scanning_generic_secrets: enabled
+private_vulnerability_reporting: enabled
+EOF
+expect_ok export_org_configs_dollar_create "created successfully" "$BIN/create_org_config" -org acme -yaml "$OUT/dollar_config.yaml" -var SEAT=seat
+expect_state export_org_configs_dollar_create_state '"description":"Costs\$5perseat".*"secret_scanning_generic_secrets":"enabled"'
+expect_ok export_org_configs_dollar "Exported 1 configuration" "$BIN/export_org_configs" -org acme -out "$OUT/configs" -config e2e-dollar
+expect_file export_org_configs_dollar_escaped "$OUT/configs/e2e-dollar.yaml" '^description: Costs \$\$5 per seat$'
+expect_ok export_org_configs_dollar_roundtrip "No changes detected" "$BIN/update_org_config" -org acme -yaml "$OUT/configs/e2e-dollar.yaml" -history-dir "$OUT/history"
+expect_ok config_copy_dry_run "appsec-oncall' does not exist in globex" "$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot -dry-run
+expect_fail config_copy_tokens "Set -from-token and -to-token" "$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot -to-ghes-url "$URL/api/v3" -dry-run
+expect_ok config_copy "Created 'pilot'" "$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot -copy-default \
+	-policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+expect_state config_copy_state '"advanced_security":"enabled".*"reviewers":\[\{"reviewer_id":601,"reviewer_type":"TEAM"\},\{"reviewer_id":5,"reviewer_type":"ROLE"\}\]' globex
+expect_state config_copy_default '"pilot":"private_and_internal"' globex
+PILOT_ID=$(curl -s -H "Authorization: Bearer $GITHUB_TOKEN" "$URL/orgs/globex/code-security/configurations" | grep -o '"id": *[0-9]*' | grep -o '[0-9]*$')
+curl -s -H "Authorization: Bearer $GITHUB_TOKEN" -X PATCH -d '{"description":"Edited in globex"}' "$URL/orgs/globex/code-security/configurations/$PILOT_ID" >/dev/null
+expect_ok config_copy_update "^- description: Edited in globex$" "$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot \
+	-policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+curl -s -H "Authorization: Bearer $GITHUB_TOKEN" -X PATCH -d '{"secret_scanning_push_protection":"disabled"}' "$URL/orgs/acme-pilot/code-security/configurations/50" >/dev/null
+expect_fail config_copy_policy "secret_scanning_push_protection: enabled -> disabled \(may not be set to disabled\)" \
+	"$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot -policy template/update_policy.yaml -yes -history-dir "$OUT/history"
+curl -s -H "Authorization: Bearer $GITHUB_TOKEN" -X PATCH -d '{"secret_scanning_push_protection":"enabled"}' "$URL/orgs/acme-pilot/code-security/configurations/50" >/dev/null
+expect_ok config_copy_history "Z +description$" "$BIN/config" history -org globex -config pilot -history-dir "$OUT/history"
+expect_ok config_copy_unchanged "No changes detected" "$BIN/config" copy -from-org acme-pilot -to-org globex -config pilot -history-dir "$OUT/history"
+# GHES wraps the created configuration in "value"; its ID is still needed to set the default
+expect_ok config_copy_ghes_create "default_for_new_repos set to private_and_internal" "$BIN/config" copy -from-org acme-pilot -from-ghes-url "$URL/api/v3" \
+	-to-org globex -to-ghes-url "$URL/api/v3" -config pilot -to-config pilot-ghes -copy-default -no-policy -yes -history-dir "$OUT/history"
+expect_state config_copy_ghes_create_default '"pilot-ghes":"private_and_internal"' globex
+expect_fail add_repo_to_config_unknown "No valid repositories" "$BIN/add_repo_to_config" -org acme -config TLC_standard -repo missing-repo -id-cache "$OUT/id-cache.json"
+
+# Rollback of the attach above: api goes back to baseline, web is detached
+expect_ok rollback_dry_run "web: TLC_standard -> \(none\)" "$BIN/rollback" -snapshot "$OUT/attach-snapshot.json" -dry-run
+expect_ok rollback "Restored 2 of 2" "$BIN/rollback" -snapshot "$OUT/attach-snapshot.json" -yes
+expect_state rollback_state '"attachments":\{"api":"baseline"\}'
+expect_ok rollback_idempotent "Nothing to restore" "$BIN/rollback" -snapshot "$OUT/attach-snapshot.json" -yes
+sed 's/"configuration_id": 1,/"configuration_id": 999,/' "$OUT/attach-snapshot.json" >"$OUT/stale-snapshot.json"
+expect_fail rollback_missing_config "no longer exists" "$BIN/rollback" -snapshot "$OUT/stale-snapshot.json" -yes
+# Configurations past the first page of the list are still valid targets
+DOLLAR_ID=$(curl -s "$URL/_fake/state/acme" | tr -d ' \n' | grep -o '"id":[0-9]*,"name":"e2e-dollar"' | grep -o '[0-9]\+' | head -1)
+sed -e "s/\"configuration_id\": 1,/\"configuration_id\": $DOLLAR_ID,/" -e 's/"configuration": "baseline"/"configuration": "e2e-dollar"/' \
+	"$OUT/attach-snapshot.json" >"$OUT/later-page-snapshot.json"
+expect_ok rollback_later_page "api: baseline -> e2e-dollar" "$BIN/rollback" -snapshot "$OUT/later-page-snapshot.json" -dry-run
+expect_fail rollback_no_endpoint "must be set either to GHEC or GHES" env GITHUB_ENDPOINT= "$BIN/rollback" -snapshot "$OUT/attach-snapshot.json" -dry-run
+expect_ok add_repo_to_config_again "attached" "$BIN/add_repo_to_config" -org acme -config TLC_standard -repo web,api -id-cache "$OUT/id-cache.json" -snapshot "$OUT/attach-snapshot-2.json"
+
+# Repository settings
+expect_ok repo_security_settings "web" "$BIN/repo_security_settings" -org acme -repo web -secret-scanning enabled -yes
+expect_state repo_security_settings_state '"name":"web"[^}]*"security_and_analysis":\{[^}]*"secret_scanning":"enabled"'
+expect_ok code_scanning_default_setup_get "configured" "$BIN/code_scanning_default_setup" -org acme -action get -repo api
+expect_ok code_scanning_default_setup_set "web" "$BIN/code_scanning_default_setup" -org acme -action set -repo web -state configured -yes
+expect_state code_scanning_default_setup_state '"web":\{[^}]*"state":"configured"'
+expect_ok code_scanning_default_setup_languages "languages" "$BIN/code_scanning_default_setup" -org acme -action set -repo web -languages go,javascript-typescript -yes
+expect_ok code_scanning_default_setup_language_order "No changes needed" "$BIN/code_scanning_default_setup" -org acme -action set -repo web -languages javascript-typescript,go -yes
+expect_ok organization_check "Organization review completed" "$BIN/organization-check"
+
+# Alerts
+expect_ok export_secret_scanning_alerts "Exported 3 secret scanning alerts" "$BIN/export_secret_scanning_alerts" -org acme -output "$OUT/secrets.json"
+if grep -Eq "ghp_|AKIA" "$OUT/secrets.json"; then fail "export_secret_scanning_redacted" export_secret_scanning_alerts; else pass "export_secret_scanning_redacted"; fi
+expect_ok triage_secret_alerts_dry_run "test fixtures" "$BIN/triage_secret_alerts" -org acme -rules template/secret_triage_rules.yaml -dry-run
+expect_ok triage_secret_alerts "used_in_tests" "$BIN/triage_secret_alerts" -org acme -rules template/secret_triage_rules.yaml -yes
+expect_state triage_secret_alerts_state '"number":2,"repo":"api","resolution":"used_in_tests"'
+# A secret that also leaked in an issue comment is not a test fixture, whatever its file paths
+expect_ok triage_secret_alerts_non_file "Resolved 1 of 1 alerts" "$BIN/triage_secret_alerts" -org acme-pilot -rules template/secret_triage_rules.yaml -yes
+expect_state triage_secret_alerts_non_file_open '"number":2,"repo":"pilot-app","secret":"[^"]*","secret_type":"aws_access_key_id","state":"open"' acme-pilot
+expect_ok push_protection_bypass_report "Bypassed alerts: 1" "$BIN/push_protection_bypass_report" -org acme -since 36500d
+expect_ok push_protection_bypass_report_empty "Wrote 0 bypass events" "$BIN/push_protection_bypass_report" -org globex -since 7d -csv "$OUT/bypass-empty.csv"
+expect_file push_protection_bypass_report_empty_csv "$OUT/bypass-empty.csv" "^source,repository,user,secret_type"
+expect_ok export_code_scanning_alerts "Exported 2 code scanning alerts" "$BIN/export_code_scanning_alerts" -org acme -output "$OUT/code.csv" -format csv -breakdown "$OUT/code-breakdown.json"
+expect_file export_code_scanning_breakdown "$OUT/code-breakdown.json" '"go/sql-injection"|CodeQL/go/sql-injection'
+expect_ok export_dependabot_alerts "lodash" "$BIN/export_dependabot_alerts" -org acme -output "$OUT/dependabot.json"
+
+# Metrics, trend and dashboard
+expect_ok metrics "Wrote metrics snapshot" "$BIN/metrics" -org acme -output "$OUT/metrics.json" -mttr-window 36500d -store "$OUT/history.jsonl"
+expect_file metrics_mttr "$OUT/metrics.json" '"mean_days": 2'
+# Configurations span several pages; the first one must not be lost
+expect_file metrics_all_configurations "$OUT/metrics.json" '"name": "baseline"'
+expect_ok trend "Only one snapshot" "$BIN/trend" -org acme -store "$OUT/history.jsonl"
+expect_ok dashboard "Wrote dashboard" "$BIN/dashboard" -org acme -store "$OUT/history.jsonl" -output "$OUT/dashboard.html"
+expect_file dashboard_rows "$OUT/dashboard.html" "<td>service-template</td>"
+
+# Web UI API
+reset
+"$BIN/serve" -org acme -addr 127.0.0.1:$((PORT + 1)) -config-dir template >"$WORK/serve.log" 2>&1 &
+SERVE_PID=$!
+for _ in $(seq 1 50); do
+	curl -s "http://127.0.0.1:$((PORT + 1))/api/yaml-files" >/dev/null && break
+	sleep 0.1
+done
+curl -s "http://127.0.0.1:$((PORT + 1))/api/configurations/1/repositories?org=acme" >"$WORK/serve_repos.log"
+expect_file serve_repositories "$WORK/serve_repos.log" '"name": "api"'
+curl -s "http://127.0.0.1:$((PORT + 1))/api/diff?org=acme&file=sample_org_config.yaml" >"$WORK/serve_diff.log"
+expect_file serve_diff "$WORK/serve_diff.log" '"field": "default_for_new_repos"'
+curl -s "http://127.0.0.1:$((PORT + 1))/api/configurations?org=acme%2F..%2F..%2Fuser" >"$WORK/serve_bad_org.log"
+expect_file serve_rejects_invalid_org "$WORK/serve_bad_org.log" "a valid org is required"
+curl -s -X POST -d '{"org":"acme","repositories":["api"],"confirm":true}' "http://127.0.0.1:$((PORT + 1))/api/detach" >"$WORK/serve_no_token.log"
+expect_file serve_requires_session_token "$WORK/serve_no_token.log" "X-Session-Token"
+# DNS rebinding: a page served under another host name must not get the UI (and its session token)
+curl -s -H "Host: rebind.example:$((PORT + 1))" "http://127.0.0.1:$((PORT + 1))/" >"$WORK/serve_rebind.log"
+expect_file serve_rejects_foreign_host "$WORK/serve_rebind.log" "unexpected Host"
+if grep -q sessionToken "$WORK/serve_rebind.log"; then fail "serve_rebind_no_token" serve_rebind; else pass "serve_rebind_no_token"; fi
+SESSION=$(curl -s "http://127.0.0.1:$((PORT + 1))/" | sed -n 's/.*sessionToken = "\([0-9a-f]*\)".*/\1/p')
+curl -s -X POST -H "X-Session-Token: $SESSION" -H "Origin: http://evil.example" -d '{"org":"acme","repositories":["api"],"confirm":true}' "http://127.0.0.1:$((PORT + 1))/api/detach" >"$WORK/serve_cross_origin.log"
+expect_file serve_rejects_cross_origin "$WORK/serve_cross_origin.log" "cross-origin request"
+curl -s -X POST -H "X-Session-Token: $SESSION" -d '{"org":"acme","repositories":["../../orgs/acme"]}' "http://127.0.0.1:$((PORT + 1))/api/detach" >"$WORK/serve_bad_repo.log"
+expect_file serve_rejects_invalid_repo "$WORK/serve_bad_repo.log" "invalid repository name"
+curl -s -X POST -H "X-Session-Token: $SESSION" -d '{"org":"acme","repositories":["api"],"confirm":true}' "http://127.0.0.1:$((PORT + 1))/api/detach" >"$WORK/serve_detach.log"
+expect_file serve_detach "$WORK/serve_detach.log" '"applied": ?true'
+kill "$SERVE_PID" 2>/dev/null
+
+# make serve: run the recipe's command without docker-compose, which publishes 127.0.0.1:$PORT as port 8080 of the container
+MAKE_SERVE_PORT=$((PORT + 4))
+SERVE_ARGS=$(make -s -n serve ORG=acme | tr -d '\r' | sed -e ':a' -e '/\\$/{N;s/\\\n//;ba' -e '}' -e 's/^.*organization-checker//' -e "s/:8080 /:$MAKE_SERVE_PORT /")
+(PORT=$MAKE_SERVE_PORT; eval "exec \"\$BIN/serve\" $SERVE_ARGS") >"$WORK/make_serve.log" 2>&1 &
+MAKE_SERVE_PID=$!
+for _ in $(seq 1 50); do
+	curl -s -H "Host: localhost:$MAKE_SERVE_PORT" "http://127.0.0.1:$MAKE_SERVE_PORT/api/yaml-files" >"$WORK/make_serve_files.log" && break
+	sleep 0.1
+done
+expect_file make_serve "$WORK/make_serve_files.log" '^\[\]'
+kill "$MAKE_SERVE_PID" 2>/dev/null
+
+# Audit log of everything the checks above changed
+expect_ok audit_show_attach "e2e-admin +attach +acme +TLC_standard +web,api" "$BIN/audit" show -org acme -action attach
+expect_ok audit_show_rollback "rollback.*detach.*web" "$BIN/audit" show -command rollback -format json
+expect_ok audit_show_repo "update_repo_security_settings" "$BIN/audit" show -repo web -since 1d
+expect_ok audit_show_detach '"before":\{"configurations":\{"api":"baseline"\}\}' "$BIN/audit" show -command serve -format json
+expect_ok audit_show_update '"update_configuration".*"before":.*"TLC Standard Security Configuration".*"after":.*"Updated by e2e"' "$BIN/audit" show -config TLC_standard -format json
+expect_ok audit_show_policy_override '"policy_violations":\[.*"override_reason":"e2e incident drill"' "$BIN/audit" show -config TLC_standard -format json
+expect_ok audit_show_revert "revert_configuration +acme +TLC_standard" "$BIN/audit" show -action 'revert_*'
+
+# Record through http_fixtures.go, then replay the fixtures without the fake server
+"$BIN/http_fixtures" -mode record -dir "$WORK/fixtures" -upstream "$URL/api/v3" -addr 127.0.0.1:$((PORT + 2)) >"$WORK/record.log" 2>&1 &
+RECORD_PID=$!
+sleep 0.5
+curl -s -X DELETE "$URL/_fake/requests" >/dev/null
+expect_ok fixtures_record "Wrote 5 repositories" env GHES_URL="http://127.0.0.1:$((PORT + 2))" "$BIN/get_org_repos" -org acme -output "$OUT/repos-recorded.yaml"
+env GHES_URL="http://127.0.0.1:$((PORT + 2))" "$BIN/export_secret_scanning_alerts" -org acme -output "$OUT/secrets-recorded.json" >/dev/null 2>&1
+kill "$RECORD_PID" 2>/dev/null
+# -upstream is the API base, every forwarded request has to stay below /api/v3
+curl -s "$URL/_fake/requests" | tr -d ' \n' | sed 's/},{/}\n{/g' >"$WORK/record-requests.log"
+if [ -s "$WORK/record-requests.log" ] && ! grep -qv '"api_prefix":"/api/v3"' "$WORK/record-requests.log"; then pass "fixtures_record_api_base"; else fail "fixtures_record_api_base" record-requests; fi
+if grep -rq '"git_url": "git://ghes.example.com/acme/' "$WORK/fixtures"; then pass "fixtures_sanitized_host"; else fail "fixtures_sanitized_host" fixtures_record; fi
+if grep -rqE "ghp_e2eFakeSecretValue0001|e2e-token|127\.0\.0\.1" "$WORK/fixtures"; then fail "fixtures_sanitized" fixtures_record; else pass "fixtures_sanitized"; fi
+"$BIN/http_fixtures" -mode replay -dir "$WORK/fixtures" -addr 127.0.0.1:$((PORT + 3)) >"$WORK/replay.log" 2>&1 &
+REPLAY_PID=$!
+sleep 0.5
+expect_ok fixtures_replay "Wrote 5 repositories" env GHES_URL="http://127.0.0.1:$((PORT + 3))" "$BIN/get_org_repos" -org acme -output "$OUT/repos-replayed.yaml"
+if cmp -s "$OUT/repos-recorded.yaml" "$OUT/repos-replayed.yaml"; then pass "fixtures_replay_identical"; else fail "fixtures_replay_identical" fixtures_replay; fi
+kill "$REPLAY_PID" 2>/dev/null
+
+echo
+echo "$PASSED passed, $FAILED failed"
+[ "$FAILED" -eq 0 ]
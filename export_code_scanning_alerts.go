@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+// CodeScanningAlert is the subset of the org code scanning alert used in the export.
+type CodeScanningAlert struct {
+	Number int    `json:"number"`
+	State  string `json:"state"`
+	Rule   struct {
+		ID                    string `json:"id"`
+		Name                  string `json:"name"`
+		Severity              string `json:"severity"`
+		SecuritySeverityLevel string `json:"security_severity_level"`
+		Description           string `json:"description"`
+	} `json:"rule"`
+	Tool struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"tool"`
+	MostRecentInstance struct {
+		Ref      string `json:"ref"`
+		Location struct {
+			Path      string `json:"path"`
+			StartLine int    `json:"start_line"`
+			EndLine   int    `json:"end_line"`
+		} `json:"location"`
+	} `json:"most_recent_instance"`
+	CreatedAt  string `json:"created_at"`
+	HTMLURL    string `json:"html_url"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+// ExportedCodeScanningAlert is one row of the JSON/CSV export.
+type ExportedCodeScanningAlert struct {
+	Repository            string `json:"repository"`
+	Number                int    `json:"number"`
+	State                 string `json:"state"`
+	Tool                  string `json:"tool"`
+	ToolVersion           string `json:"tool_version"`
+	RuleID                string `json:"rule_id"`
+	RuleDescription       string `json:"rule_description"`
+	Severity              string `json:"severity"`
+	SecuritySeverityLevel string `json:"security_severity_level"`
+	Ref                   string `json:"ref"`
+	Path                  string `json:"path"`
+	StartLine             int    `json:"start_line"`
+	EndLine               int    `json:"end_line"`
+	CreatedAt             string `json:"created_at"`
+	URL                   string `json:"html_url"`
+}
+
+// BreakdownEntry counts alerts for one repository, tool or rule.
+type BreakdownEntry struct {
+	Name       string         `json:"name"`
+	Total      int            `json:"total"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// Breakdown is the aggregated view written next to the export.
+type Breakdown struct {
+	Total        int              `json:"total"`
+	ByRepository []BreakdownEntry `json:"by_repository"`
+	ByTool       []BreakdownEntry `json:"by_tool"`
+	ByRule       []BreakdownEntry `json:"by_rule"`
+}
+
+func fetchCodeScanningAlerts(client *http.Client, apiBase, org, token string, query url.Values) ([]CodeScanningAlert, error) {
+	var alerts []CodeScanningAlert
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-scanning/alerts?%s", apiBase, org, query.Encode()), token, &alerts); err != nil {
+		return nil, fmt.Errorf("failed to list code scanning alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// effectiveSeverity prefers the security severity (critical/high/medium/low)
+// and falls back to the rule severity (error/warning/note).
+func effectiveSeverity(r ExportedCodeScanningAlert) string {
+	if r.SecuritySeverityLevel != "" {
+		return r.SecuritySeverityLevel
+	}
+	if r.Severity != "" {
+		return r.Severity
+	}
+	return "none"
+}
+
+func groupBy(rows []ExportedCodeScanningAlert, key func(ExportedCodeScanningAlert) string) []BreakdownEntry {
+	entries := make(map[string]*BreakdownEntry)
+	for _, r := range rows {
+		k := key(r)
+		e, ok := entries[k]
+		if !ok {
+			e = &BreakdownEntry{Name: k, BySeverity: map[string]int{}}
+			entries[k] = e
+		}
+		e.Total++
+		e.BySeverity[effectiveSeverity(r)]++
+	}
+	list := make([]BreakdownEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func printBreakdown(title string, entries []BreakdownEntry, limit int) {
+	fmt.Printf("\n%s\n", title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tTOTAL\tCRITICAL\tHIGH\tMEDIUM\tLOW\tOTHER")
+	for i, e := range entries {
+		if limit > 0 && i >= limit {
+			fmt.Fprintf(w, "  ... %d more\t\t\t\t\t\t\n", len(entries)-limit)
+			break
+		}
+		other := e.Total - e.BySeverity["critical"] - e.BySeverity["high"] - e.BySeverity["medium"] - e.BySeverity["low"]
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%d\t%d\t%d\n", e.Name, e.Total, e.BySeverity["critical"], e.BySeverity["high"], e.BySeverity["medium"], e.BySeverity["low"], other)
+	}
+	w.Flush()
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	state := flag.String("state", "open", "Alert state: open, closed, dismissed, fixed (empty for all)")
+	toolName := flag.String("tool-name", "", "Only alerts from this tool (e.g. CodeQL)")
+	severity := flag.String("severity", "", "Only alerts of this severity: critical, high, medium, low, warning, note, error")
+	format := flag.String("format", "json", "Output format: json or csv")
+	output := flag.String("output", "", "Output file (default workspace/<org>-code-scanning-alerts.<format>)")
+	breakdownPath := flag.String("breakdown", "", "Breakdown JSON file (default workspace/<org>-code-scanning-breakdown.json)")
+	top := flag.Int("top", 20, "Rows shown per breakdown table (0 for all)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" {
+		log.Fatal("Usage: go run export_code_scanning_alerts.go -org <org> [-state open] [-tool-name CodeQL] [-severity high] [-format json|csv]")
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("Invalid -format '%s': must be json or csv", *format)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("workspace/%s-code-scanning-alerts.%s", *org, *format)
+	}
+	if *breakdownPath == "" {
+		*breakdownPath = fmt.Sprintf("workspace/%s-code-scanning-breakdown.json", *org)
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	query := url.Values{}
+	query.Set("per_page", "100")
+	if *state != "" {
+		query.Set("state", *state)
+	}
+	if *toolName != "" {
+		query.Set("tool_name", *toolName)
+	}
+	if *severity != "" {
+		query.Set("severity", *severity)
+	}
+
+	client := &http.Client{}
+	alerts, err := fetchCodeScanningAlerts(client, apiBase, *org, githubToken, query)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	rows := make([]ExportedCodeScanningAlert, 0, len(alerts))
+	for _, a := range alerts {
+		rows = append(rows, ExportedCodeScanningAlert{
+			Repository:            a.Repository.Name,
+			Number:                a.Number,
+			State:                 a.State,
+			Tool:                  a.Tool.Name,
+			ToolVersion:           a.Tool.Version,
+			RuleID:                a.Rule.ID,
+			RuleDescription:       a.Rule.Description,
+			Severity:              a.Rule.Severity,
+			SecuritySeverityLevel: a.Rule.SecuritySeverityLevel,
+			Ref:                   a.MostRecentInstance.Ref,
+			Path:                  a.MostRecentInstance.Location.Path,
+			StartLine:             a.MostRecentInstance.Location.StartLine,
+			EndLine:               a.MostRecentInstance.Location.EndLine,
+			CreatedAt:             a.CreatedAt,
+			URL:                   a.HTMLURL,
+		})
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *output, err)
+	}
+	defer f.Close()
+	if *format == "csv" {
+		w := csv.NewWriter(f)
+		w.Write([]string{"repository", "number", "state", "tool", "tool_version", "rule_id", "rule_description", "severity", "security_severity_level", "ref", "path", "start_line", "end_line", "created_at", "html_url"})
+		for _, r := range rows {
+			w.Write([]string{r.Repository, strconv.Itoa(r.Number), r.State, r.Tool, r.ToolVersion, r.RuleID, r.RuleDescription, r.Severity, r.SecuritySeverityLevel, r.Ref, r.Path, strconv.Itoa(r.StartLine), strconv.Itoa(r.EndLine), r.CreatedAt, r.URL})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			log.Fatalf("Failed to write JSON: %v", err)
+		}
+	}
+	fmt.Printf("Exported %d code scanning alerts to %s\n", len(rows), *output)
+
+	breakdown := Breakdown{
+		Total:        len(rows),
+		ByRepository: groupBy(rows, func(r ExportedCodeScanningAlert) string { return r.Repository }),
+		ByTool:       groupBy(rows, func(r ExportedCodeScanningAlert) string { return r.Tool }),
+		ByRule: groupBy(rows, func(r ExportedCodeScanningAlert) string {
+			return r.Tool + "/" + r.RuleID
+		}),
+	}
+	data, err := json.MarshalIndent(breakdown, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal breakdown JSON: %v", err)
+	}
+	if err := ioutil.WriteFile(*breakdownPath, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *breakdownPath, err)
+	}
+	fmt.Printf("Wrote breakdown to %s\n", *breakdownPath)
+
+	if len(rows) == 0 {
+		return
+	}
+	printBreakdown("By repository", breakdown.ByRepository, *top)
+	printBreakdown("By tool", breakdown.ByTool, *top)
+	printBreakdown("By rule", breakdown.ByRule, *top)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+type DependabotAlert struct {
+	Number     int    `json:"number"`
+	State      string `json:"state"`
+	Dependency struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		ManifestPath string `json:"manifest_path"`
+		Scope        string `json:"scope"`
+	} `json:"dependency"`
+	SecurityAdvisory struct {
+		GHSAID   string `json:"ghsa_id"`
+		CVEID    string `json:"cve_id"`
+		Summary  string `json:"summary"`
+		Severity string `json:"severity"`
+	} `json:"security_advisory"`
+	SecurityVulnerability struct {
+		Severity               string `json:"severity"`
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+		FirstPatchedVersion    *struct {
+			Identifier string `json:"identifier"`
+		} `json:"first_patched_version"`
+	} `json:"security_vulnerability"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FixedAt    *time.Time `json:"fixed_at"`
+	HTMLURL    string     `json:"html_url"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+// ExportedDependabotAlert is one row of the JSON/CSV export.
+type ExportedDependabotAlert struct {
+	Repository   string `json:"repository"`
+	Number       int    `json:"number"`
+	State        string `json:"state"`
+	Severity     string `json:"severity"`
+	Ecosystem    string `json:"ecosystem"`
+	Package      string `json:"package"`
+	ManifestPath string `json:"manifest_path"`
+	Scope        string `json:"scope"`
+	GHSAID       string `json:"ghsa_id"`
+	CVEID        string `json:"cve_id"`
+	Summary      string `json:"summary"`
+	CreatedAt    string `json:"created_at"`
+	AgeDays      int    `json:"age_days"`
+	FixAvailable bool   `json:"fix_available"`
+	PatchedIn    string `json:"patched_version"`
+	SLADays      int    `json:"sla_days"`
+	Overdue      bool   `json:"overdue"`
+	OverdueDays  int    `json:"overdue_days"`
+	URL          string `json:"html_url"`
+}
+
+var severityOrder = []string{"critical", "high", "medium", "low"}
+
+// parseSLA reads "critical=7,high=30" into days per severity.
+func parseSLA(s string) (map[string]int, error) {
+	sla := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid SLA entry '%s', expected severity=days", part)
+		}
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(kv[1]), "d"))
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid SLA days in '%s'", part)
+		}
+		sla[strings.ToLower(strings.TrimSpace(kv[0]))] = days
+	}
+	return sla, nil
+}
+
+// fetchDependabotAlerts follows the cursor-based Link pagination of the org alerts endpoint.
+func fetchDependabotAlerts(client *http.Client, apiBase, org, token string, query url.Values) ([]DependabotAlert, error) {
+	var alerts []DependabotAlert
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/dependabot/alerts?%s", apiBase, org, query.Encode()), token, &alerts); err != nil {
+		return nil, fmt.Errorf("failed to list Dependabot alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func printSLAReport(rows []ExportedDependabotAlert, sla map[string]int, limit int) {
+	type stats struct{ open, overdue, fixable, oldest int }
+	bySeverity := make(map[string]*stats)
+	var overdue []ExportedDependabotAlert
+	for _, r := range rows {
+		if r.State != "open" {
+			continue
+		}
+		st, ok := bySeverity[r.Severity]
+		if !ok {
+			st = &stats{}
+			bySeverity[r.Severity] = st
+		}
+		st.open++
+		if r.FixAvailable {
+			st.fixable++
+		}
+		if r.AgeDays > st.oldest {
+			st.oldest = r.AgeDays
+		}
+		if r.Overdue {
+			st.overdue++
+			overdue = append(overdue, r)
+		}
+	}
+
+	fmt.Println("\nDependabot SLA report (open alerts)")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  SEVERITY\tSLA (days)\tOPEN\tOVERDUE\tFIX AVAILABLE\tOLDEST (days)")
+	for _, sev := range severityOrder {
+		st, ok := bySeverity[sev]
+		if !ok {
+			st = &stats{}
+		}
+		slaText := "-"
+		if days, ok := sla[sev]; ok {
+			slaText = strconv.Itoa(days)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%d\t%d\t%d\n", sev, slaText, st.open, st.overdue, st.fixable, st.oldest)
+	}
+	w.Flush()
+
+	if len(overdue) == 0 {
+		fmt.Println("\n✅ No open alerts past their SLA.")
+		return
+	}
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].OverdueDays > overdue[j].OverdueDays })
+	fmt.Printf("\n❌ %d open alerts past their SLA", len(overdue))
+	if limit > 0 && len(overdue) > limit {
+		fmt.Printf(" (showing %d most overdue)", limit)
+		overdue = overdue[:limit]
+	}
+	fmt.Println(":")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  REPOSITORY\tALERT\tSEVERITY\tPACKAGE\tAGE\tOVERDUE BY\tFIX")
+	for _, r := range overdue {
+		fix := "no"
+		if r.FixAvailable {
+			fix = r.PatchedIn
+		}
+		fmt.Fprintf(w, "  %s\t#%d\t%s\t%s:%s\t%dd\t%dd\t%s\n", r.Repository, r.Number, r.Severity, r.Ecosystem, r.Package, r.AgeDays, r.OverdueDays, fix)
+	}
+	w.Flush()
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	state := flag.String("state", "open", "Comma-separated states: open, fixed, dismissed, auto_dismissed (empty for all)")
+	severity := flag.String("severity", "", "Comma-separated severities: critical, high, medium, low")
+	ecosystem := flag.String("ecosystem", "", "Comma-separated ecosystems (e.g. npm,maven,pip)")
+	format := flag.String("format", "json", "Output format: json or csv")
+	output := flag.String("output", "", "Output file (default workspace/<org>-dependabot-alerts.<format>)")
+	slaFlag := flag.String("sla", "critical=7,high=30,medium=90,low=180", "Remediation deadline in days per severity")
+	overdueLimit := flag.Int("overdue-limit", 50, "Maximum overdue alerts listed in the SLA report (0 for all)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" {
+		log.Fatal("Usage: go run export_dependabot_alerts.go -org <org> [-state open] [-severity critical,high] [-ecosystem npm] [-format json|csv] [-sla critical=7,high=30]")
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("Invalid -format '%s': must be json or csv", *format)
+	}
+	sla, err := parseSLA(*slaFlag)
+	if err != nil {
+		log.Fatalf("Invalid -sla: %v", err)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("workspace/%s-dependabot-alerts.%s", *org, *format)
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	query := url.Values{}
+	query.Set("per_page", "100")
+	if *state != "" {
+		query.Set("state", *state)
+	}
+	if *severity != "" {
+		query.Set("severity", *severity)
+	}
+	if *ecosystem != "" {
+		query.Set("ecosystem", *ecosystem)
+	}
+
+	client := &http.Client{}
+	alerts, err := fetchDependabotAlerts(client, apiBase, *org, githubToken, query)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	now := time.Now()
+	rows := make([]ExportedDependabotAlert, 0, len(alerts))
+	for _, a := range alerts {
+		sev := strings.ToLower(a.SecurityVulnerability.Severity)
+		if sev == "" {
+			sev = strings.ToLower(a.SecurityAdvisory.Severity)
+		}
+		// Age runs until the fix for fixed alerts, otherwise until now
+		end := now
+		if a.FixedAt != nil {
+			end = *a.FixedAt
+		}
+		row := ExportedDependabotAlert{
+			Repository:   a.Repository.Name,
+			Number:       a.Number,
+			State:        a.State,
+			Severity:     sev,
+			Ecosystem:    a.Dependency.Package.Ecosystem,
+			Package:      a.Dependency.Package.Name,
+			ManifestPath: a.Dependency.ManifestPath,
+			Scope:        a.Dependency.Scope,
+			GHSAID:       a.SecurityAdvisory.GHSAID,
+			CVEID:        a.SecurityAdvisory.CVEID,
+			Summary:      a.SecurityAdvisory.Summary,
+			CreatedAt:    a.CreatedAt.UTC().Format(time.RFC3339),
+			AgeDays:      int(end.Sub(a.CreatedAt).Hours() / 24),
+			URL:          a.HTMLURL,
+		}
+		if a.SecurityVulnerability.FirstPatchedVersion != nil {
+			row.FixAvailable = true
+			row.PatchedIn = a.SecurityVulnerability.FirstPatchedVersion.Identifier
+		}
+		if days, ok := sla[sev]; ok {
+			row.SLADays = days
+			if a.State == "open" && row.AgeDays > days {
+				row.Overdue = true
+				row.OverdueDays = row.AgeDays - days
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *output, err)
+	}
+	defer f.Close()
+	if *format == "csv" {
+		w := csv.NewWriter(f)
+		w.Write([]string{"repository", "number", "state", "severity", "ecosystem", "package", "manifest_path", "scope", "ghsa_id", "cve_id", "summary", "created_at", "age_days", "fix_available", "patched_version", "sla_days", "overdue", "overdue_days", "html_url"})
+		for _, r := range rows {
+			w.Write([]string{r.Repository, strconv.Itoa(r.Number), r.State, r.Severity, r.Ecosystem, r.Package, r.ManifestPath, r.Scope, r.GHSAID, r.CVEID, r.Summary, r.CreatedAt, strconv.Itoa(r.AgeDays), strconv.FormatBool(r.FixAvailable), r.PatchedIn, strconv.Itoa(r.SLADays), strconv.FormatBool(r.Overdue), strconv.Itoa(r.OverdueDays), r.URL})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			log.Fatalf("Failed to write JSON: %v", err)
+		}
+	}
+	fmt.Printf("Exported %d Dependabot alerts to %s\n", len(rows), *output)
+
+	printSLAReport(rows, sla, *overdueLimit)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github-secret-scanning/internal/codesecurity"
+	"github-secret-scanning/internal/ghapi"
+)
+
+// exportYAML renders a configuration as a YAML file create_org_config.go and update_org_config.go can read.
+func exportYAML(cfg codesecurity.Config, org, endpoint, applyPath string) ([]byte, error) {
+	// Keep only the values GitHub actually returned for the nested options
+	if o := cfg.CodeScanningDefaultSetupOptions; o != nil && o.RunnerType == "" && o.RunnerLabel == nil {
+		cfg.CodeScanningDefaultSetupOptions = nil
+	}
+	if o := cfg.SecretScanningDelegatedBypassOptions; o != nil && len(o.Reviewers) == 0 {
+		cfg.SecretScanningDelegatedBypassOptions = nil
+	}
+	body, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// create and update substitute ${VAR} references, $$ reads back as a literal $
+	body = bytes.ReplaceAll(body, []byte("$"), []byte("$$"))
+	header := fmt.Sprintf("# Code security configuration '%s' exported from organization '%s' (%s)\n", cfg.Name, org, endpoint)
+	header += fmt.Sprintf("# exported: %s\n", time.Now().UTC().Format(time.RFC3339))
+	header += fmt.Sprintf("# Apply changes with: go run update_org_config.go -org %s -yaml %s\n\n", org, applyPath)
+	return append([]byte(header), body...), nil
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	outDir := flag.String("out", "", "Directory the YAML files are written to (default workspace/<org>-configs)")
+	configFilter := flag.String("config", "", "Only export configurations whose name matches this glob")
+	force := flag.Bool("force", false, "Overwrite YAML files that already exist")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" {
+		log.Fatal("Usage: go run export_org_configs.go -org <org> [-out workspace/<org>-configs] [-config glob] [-force]")
+	}
+	if *outDir == "" {
+		*outDir = fmt.Sprintf("workspace/%s-configs", *org)
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase, endpointLabel string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase, endpointLabel = "https://api.github.com", "GHEC"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase, endpointLabel = *ghesURL, "GHES"
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	var configs []codesecurity.Config
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", apiBase, *org), githubToken, &configs); err != nil {
+		log.Fatalf("Failed to list configurations: %v", err)
+	}
+	var defaults []codesecurity.Default
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations/defaults", apiBase, *org), githubToken, &defaults); err != nil {
+		log.Fatalf("Failed to read default configurations: %v", err)
+	}
+	defaultFor := make(map[int]string)
+	for _, d := range defaults {
+		defaultFor[d.Configuration.ID] = d.DefaultForNewRepos
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *outDir, err)
+	}
+	written, skipped := 0, 0
+	usedNames := make(map[string]string)
+	for _, cfg := range configs {
+		if cfg.TargetType == "global" {
+			// GitHub-managed configurations cannot be created or updated from YAML
+			fmt.Printf("⏭️  Skipping '%s': managed by GitHub\n", cfg.Name)
+			continue
+		}
+		if *configFilter != "" {
+			if ok, _ := path.Match(strings.ToLower(*configFilter), strings.ToLower(cfg.Name)); !ok {
+				continue
+			}
+		}
+		cfg.DefaultForNewRepos = defaultFor[cfg.ID]
+		if cfg.DefaultForNewRepos == "" {
+			cfg.DefaultForNewRepos = "none"
+		}
+
+		fileName := codesecurity.FileName(cfg.Name) + ".yaml"
+		if other, ok := usedNames[fileName]; ok {
+			log.Fatalf("Configurations '%s' and '%s' would both be written to %s", other, cfg.Name, fileName)
+		}
+		usedNames[fileName] = cfg.Name
+		outPath := filepath.Join(*outDir, fileName)
+		if _, err := os.Stat(outPath); err == nil && !*force {
+			fmt.Printf("⚠️  %s already exists, use -force to overwrite\n", outPath)
+			skipped++
+			continue
+		}
+		data, err := exportYAML(cfg, *org, endpointLabel, outPath)
+		if err != nil {
+			log.Fatalf("Failed to render '%s': %v", cfg.Name, err)
+		}
+		if err := ioutil.WriteFile(outPath, data, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", outPath, err)
+		}
+		fmt.Printf("✅ %s → %s (default_for_new_repos: %s)\n", cfg.Name, outPath, cfg.DefaultForNewRepos)
+		written++
+	}
+
+	if written == 0 && skipped == 0 {
+		fmt.Printf("No configurations to export from '%s'.\n", *org)
+		return
+	}
+	fmt.Printf("\n📦 Exported %d configuration(s) from '%s' to %s", written, *org, *outDir)
+	if skipped > 0 {
+		fmt.Printf(" (%d skipped)", skipped)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,1026 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Seed is the initial state of the fake server, loaded from -seed.
+type Seed struct {
+	User   string              `json:"user"`
+	Orgs   map[string]*SeedOrg `json:"orgs"`
+	Faults []*Fault            `json:"faults"`
+}
+
+// SeedOrg describes one organization. Alerts are free-form GitHub alert objects with an extra "repo" key.
+type SeedOrg struct {
+	Repos                []*FakeRepo                       `json:"repos"`
+	Properties           map[string]map[string]string      `json:"properties"`
+	Configurations       []map[string]interface{}          `json:"configurations"`
+	Attachments          map[string]string                 `json:"attachments"`
+	DefaultSetup         map[string]map[string]interface{} `json:"default_setup"`
+	SecretScanningAlerts []map[string]interface{}          `json:"secret_scanning_alerts"`
+	CodeScanningAlerts   []map[string]interface{}          `json:"code_scanning_alerts"`
+	DependabotAlerts     []map[string]interface{}          `json:"dependabot_alerts"`
+	BypassRequests       []map[string]interface{}          `json:"bypass_requests"`
+	Teams                []map[string]interface{}          `json:"teams"`
+}
+
+type FakeRepo struct {
+	ID                  int               `json:"id"`
+	Name                string            `json:"name"`
+	Visibility          string            `json:"visibility"`
+	Archived            bool              `json:"archived"`
+	Fork                bool              `json:"fork"`
+	IsTemplate          bool              `json:"is_template"`
+	Language            string            `json:"language"`
+	Topics              []string          `json:"topics"`
+	PushedAt            string            `json:"pushed_at"`
+	SecurityAndAnalysis map[string]string `json:"security_and_analysis"`
+}
+
+// Fault makes matching requests fail. Path is matched per segment with path.Match, so "/orgs/*/repos" works.
+type Fault struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	RateLimit bool   `json:"rate_limit"`
+	After     int    `json:"after"`
+	Times     int    `json:"times"`
+	seen      int
+	fired     int
+}
+
+type attachment struct {
+	ConfigID int
+	Status   string
+}
+
+type orgState struct {
+	name           string
+	repos          []*FakeRepo
+	properties     map[string]map[string]string
+	configurations []map[string]interface{}
+	defaults       map[int]string
+	attachments    map[string]attachment
+	defaultSetup   map[string]map[string]interface{}
+	secretAlerts   []map[string]interface{}
+	codeAlerts     []map[string]interface{}
+	depAlerts      []map[string]interface{}
+	bypassRequests []map[string]interface{}
+	teams          []map[string]interface{}
+}
+
+// RecordedRequest is one request seen by the server, exposed on /_fake/requests for assertions.
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Status int             `json:"status"`
+	// APIPrefix is /api/v3 when the request came in below it, as go-github's enterprise client and GHES_URL do.
+	APIPrefix string `json:"api_prefix,omitempty"`
+}
+
+type fakeServer struct {
+	mu         sync.Mutex
+	seedPath   string
+	token      string
+	maxPerPage int
+	apiPrefix  string
+	user       string
+	orgs       map[string]*orgState
+	faults     []*Fault
+	requests   []RecordedRequest
+	nextID     int
+	mux        *http.ServeMux
+}
+
+func (s *fakeServer) load() error {
+	seed := Seed{}
+	if s.seedPath != "" {
+		data, err := ioutil.ReadFile(s.seedPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &seed); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", s.seedPath, err)
+		}
+	}
+	s.user = seed.User
+	if s.user == "" {
+		s.user = "octocat"
+	}
+	s.orgs = make(map[string]*orgState)
+	s.faults = seed.Faults
+	s.requests = nil
+	s.nextID = 1000
+	// Sorted so generated IDs do not depend on map order
+	names := make([]string, 0, len(seed.Orgs))
+	for name := range seed.Orgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		so := seed.Orgs[name]
+		o := &orgState{
+			name:           name,
+			repos:          so.Repos,
+			properties:     so.Properties,
+			defaults:       make(map[int]string),
+			attachments:    make(map[string]attachment),
+			defaultSetup:   so.DefaultSetup,
+			secretAlerts:   numberAlerts(so.SecretScanningAlerts),
+			codeAlerts:     numberAlerts(so.CodeScanningAlerts),
+			depAlerts:      numberAlerts(so.DependabotAlerts),
+			bypassRequests: numberAlerts(so.BypassRequests),
+			teams:          so.Teams,
+		}
+		if o.properties == nil {
+			o.properties = make(map[string]map[string]string)
+		}
+		if o.defaultSetup == nil {
+			o.defaultSetup = make(map[string]map[string]interface{})
+		}
+		for _, r := range o.repos {
+			if r.ID == 0 {
+				r.ID = s.newID()
+			}
+			if r.Visibility == "" {
+				r.Visibility = "private"
+			}
+		}
+		for _, c := range so.Configurations {
+			if _, ok := c["id"]; !ok {
+				c["id"] = s.newID()
+			}
+			c["id"] = toInt(c["id"])
+			if d, ok := c["default_for_new_repos"].(string); ok {
+				o.defaults[c["id"].(int)] = d
+				delete(c, "default_for_new_repos")
+			}
+			o.configurations = append(o.configurations, c)
+		}
+		for repo, configName := range so.Attachments {
+			if c := o.configByName(configName); c != nil {
+				o.attachments[repo] = attachment{ConfigID: c["id"].(int), Status: "attached"}
+			}
+		}
+		s.orgs[strings.ToLower(name)] = o
+	}
+	return nil
+}
+
+func (s *fakeServer) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+// numberAlerts gives seed alerts without a number sequential numbers.
+func numberAlerts(alerts []map[string]interface{}) []map[string]interface{} {
+	for i, a := range alerts {
+		if _, ok := a["number"]; !ok {
+			a["number"] = i + 1
+		}
+		a["number"] = toInt(a["number"])
+	}
+	return alerts
+}
+
+func (o *orgState) repo(name string) *FakeRepo {
+	for _, r := range o.repos {
+		if strings.EqualFold(r.Name, name) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (o *orgState) repoByID(id int) *FakeRepo {
+	for _, r := range o.repos {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (o *orgState) config(id int) map[string]interface{} {
+	for _, c := range o.configurations {
+		if c["id"] == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (o *orgState) configByName(name string) map[string]interface{} {
+	for _, c := range o.configurations {
+		if c["name"] == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// render builds the API representation of a repository.
+func (s *fakeServer) render(o *orgState, r *FakeRepo, base string) map[string]interface{} {
+	sa := make(map[string]interface{})
+	for k, v := range r.SecurityAndAnalysis {
+		sa[k] = map[string]string{"status": v}
+	}
+	topics := r.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	host := strings.TrimPrefix(base, "http://")
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	return map[string]interface{}{
+		"id":                    r.ID,
+		"name":                  r.Name,
+		"full_name":             o.name + "/" + r.Name,
+		"owner":                 map[string]interface{}{"login": o.name, "type": "Organization"},
+		"private":               r.Visibility != "public",
+		"visibility":            r.Visibility,
+		"archived":              r.Archived,
+		"fork":                  r.Fork,
+		"is_template":           r.IsTemplate,
+		"language":              r.Language,
+		"topics":                topics,
+		"pushed_at":             r.PushedAt,
+		"html_url":              base + "/" + o.name + "/" + r.Name,
+		"git_url":               "git://" + host + "/" + o.name + "/" + r.Name + ".git",
+		"ssh_url":               "git@" + hostname + ":" + o.name + "/" + r.Name + ".git",
+		"security_and_analysis": sa,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message, "documentation_url": "https://docs.github.com/rest"})
+}
+
+// paginate serves one page of items with a GitHub style Link header.
+func (s *fakeServer) paginate(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	if s.maxPerPage > 0 && perPage > s.maxPerPage {
+		perPage = s.maxPerPage
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	lastPage := (len(items) + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+	start := (page - 1) * perPage
+	end := start + perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	pageURL := func(p int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return fmt.Sprintf("http://%s%s%s?%s", r.Host, s.apiPrefix, r.URL.Path, q.Encode())
+	}
+	var links []string
+	if page < lastPage {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)), fmt.Sprintf(`<%s>; rel="last"`, pageURL(lastPage)))
+	}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(page-1)), fmt.Sprintf(`<%s>; rel="first"`, pageURL(1)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	writeJSON(w, http.StatusOK, items[start:end])
+}
+
+// matchFault returns the fault to inject for this request, if any.
+func (s *fakeServer) matchFault(r *http.Request) *Fault {
+	for _, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+				continue
+			}
+		}
+		f.seen++
+		if f.seen <= f.After {
+			continue
+		}
+		if f.Times > 0 && f.fired >= f.Times {
+			continue
+		}
+		f.fired++
+		return f
+	}
+	return nil
+}
+
+// statusRecorder captures the status code for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// go-github's enterprise client prefixes every path with /api/v3, so do GHES_URLs ending in it;
+	// the prefix is optional here but kept in Link headers like a real instance would
+	s.apiPrefix = ""
+	if strings.HasPrefix(r.URL.Path, "/api/v3/") {
+		s.apiPrefix = "/api/v3"
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, s.apiPrefix)
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		if strings.HasPrefix(r.URL.Path, "/_fake/") {
+			return
+		}
+		entry := RecordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Status: rec.status, APIPrefix: s.apiPrefix}
+		if json.Valid(body) {
+			entry.Body = body
+		}
+		s.requests = append(s.requests, entry)
+	}()
+
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	if !strings.HasPrefix(r.URL.Path, "/_fake/") {
+		auth := r.Header.Get("Authorization")
+		if auth == "" || (s.token != "" && auth != "Bearer "+s.token && auth != "token "+s.token) {
+			writeMessage(rec, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+		if f := s.matchFault(r); f != nil {
+			status := f.Status
+			message := f.Message
+			if f.RateLimit {
+				if status == 0 {
+					status = http.StatusForbidden
+				}
+				if message == "" {
+					message = "API rate limit exceeded"
+				}
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("Retry-After", "1")
+			}
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			writeMessage(rec, status, message)
+			return
+		}
+	}
+	s.mux.ServeHTTP(rec, r)
+}
+
+// withOrg resolves the {org} or {owner} path value and 404s when the org is unknown.
+func (s *fakeServer) withOrg(h func(http.ResponseWriter, *http.Request, *orgState)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("org")
+		if name == "" {
+			name = r.PathValue("owner")
+		}
+		o, ok := s.orgs[strings.ToLower(name)]
+		if !ok {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, o)
+	}
+}
+
+// withRepo additionally resolves {repo}.
+func (s *fakeServer) withRepo(h func(http.ResponseWriter, *http.Request, *orgState, *FakeRepo)) http.HandlerFunc {
+	return s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		repo := o.repo(r.PathValue("repo"))
+		if repo == nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, o, repo)
+	})
+}
+
+func baseURL(r *http.Request) string {
+	return "http://" + r.Host
+}
+
+// filterAlerts applies the comma-separated query filters shared by the alert endpoints.
+func filterAlerts(r *http.Request, o *orgState, alerts []map[string]interface{}, filters map[string]func(map[string]interface{}) string) []interface{} {
+	items := []interface{}{}
+	for _, a := range alerts {
+		keep := true
+		for param, get := range filters {
+			want := r.URL.Query().Get(param)
+			if want == "" {
+				continue
+			}
+			match := false
+			for _, v := range strings.Split(want, ",") {
+				if strings.EqualFold(strings.TrimSpace(v), get(a)) {
+					match = true
+				}
+			}
+			keep = keep && match
+		}
+		if !keep {
+			continue
+		}
+		out := make(map[string]interface{}, len(a)+2)
+		for k, v := range a {
+			if k != "repo" && k != "locations" {
+				out[k] = v
+			}
+		}
+		repoName, _ := a["repo"].(string)
+		if repo := o.repo(repoName); repo != nil {
+			out["repository"] = map[string]interface{}{"id": repo.ID, "name": repo.Name, "full_name": o.name + "/" + repo.Name}
+		}
+		if _, ok := out["html_url"]; !ok {
+			out["html_url"] = fmt.Sprintf("%s/%s/%s/security/%d", baseURL(r), o.name, repoName, a["number"])
+		}
+		items = append(items, out)
+	}
+	return items
+}
+
+func str(path ...string) func(map[string]interface{}) string {
+	return func(a map[string]interface{}) string {
+		var v interface{} = a
+		for _, p := range path {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			v = m[p]
+		}
+		s, _ := v.(string)
+		return s
+	}
+}
+
+func (s *fakeServer) routes() {
+	m := http.NewServeMux()
+	s.mux = m
+
+	// Users and organizations
+	m.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"login": s.user, "id": 1, "type": "User"})
+	})
+	m.HandleFunc("GET /user/orgs", func(w http.ResponseWriter, r *http.Request) {
+		names := make([]string, 0, len(s.orgs))
+		for _, o := range s.orgs {
+			names = append(names, o.name)
+		}
+		sort.Strings(names)
+		items := []interface{}{}
+		for i, n := range names {
+			items = append(items, map[string]interface{}{"login": n, "id": i + 1})
+		}
+		s.paginate(w, r, items)
+	})
+	m.HandleFunc("GET /user/memberships/orgs/{org}", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"state": "active", "role": "admin", "organization": map[string]string{"login": o.name}})
+	}))
+	m.HandleFunc("GET /orgs/{org}", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"login": o.name, "id": 1})
+	}))
+
+	// Repositories and custom properties
+	m.HandleFunc("GET /orgs/{org}/repos", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := []interface{}{}
+		for _, repo := range o.repos {
+			switch r.URL.Query().Get("type") {
+			case "public":
+				if repo.Visibility != "public" {
+					continue
+				}
+			case "private":
+				if repo.Visibility == "public" {
+					continue
+				}
+			case "forks":
+				if !repo.Fork {
+					continue
+				}
+			case "sources":
+				if repo.Fork {
+					continue
+				}
+			}
+			items = append(items, s.render(o, repo, baseURL(r)))
+		}
+		s.paginate(w, r, items)
+	}))
+	m.HandleFunc("GET /repos/{owner}/{repo}", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		writeJSON(w, http.StatusOK, s.render(o, repo, baseURL(r)))
+	}))
+	m.HandleFunc("PATCH /repos/{owner}/{repo}", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		var req struct {
+			SecurityAndAnalysis map[string]struct {
+				Status string `json:"status"`
+			} `json:"security_and_analysis"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		if repo.SecurityAndAnalysis == nil {
+			repo.SecurityAndAnalysis = make(map[string]string)
+		}
+		for k, v := range req.SecurityAndAnalysis {
+			repo.SecurityAndAnalysis[k] = v.Status
+		}
+		writeJSON(w, http.StatusOK, s.render(o, repo, baseURL(r)))
+	}))
+	propertyValues := func(props map[string]string) []interface{} {
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := []interface{}{}
+		for _, k := range keys {
+			values = append(values, map[string]string{"property_name": k, "value": props[k]})
+		}
+		return values
+	}
+	repoProperties := s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		writeJSON(w, http.StatusOK, propertyValues(o.properties[repo.Name]))
+	})
+	m.HandleFunc("GET /repos/{owner}/{repo}/properties/values", repoProperties)
+	m.HandleFunc("GET /repos/{owner}/{repo}/properties", repoProperties)
+	m.HandleFunc("GET /orgs/{org}/properties/values", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := []interface{}{}
+		for _, repo := range o.repos {
+			items = append(items, map[string]interface{}{
+				"repository_id":        repo.ID,
+				"repository_name":      repo.Name,
+				"repository_full_name": o.name + "/" + repo.Name,
+				"properties":           propertyValues(o.properties[repo.Name]),
+			})
+		}
+		s.paginate(w, r, items)
+	}))
+
+	m.HandleFunc("GET /orgs/{org}/teams", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := []interface{}{}
+		for _, t := range o.teams {
+			items = append(items, t)
+		}
+		s.paginate(w, r, items)
+	}))
+
+	// Code security configurations
+	m.HandleFunc("GET /orgs/{org}/code-security/configurations", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := []interface{}{}
+		for _, c := range o.configurations {
+			items = append(items, c)
+		}
+		s.paginate(w, r, items)
+	}))
+	m.HandleFunc("POST /orgs/{org}/code-security/configurations", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		var c map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		name, _ := c["name"].(string)
+		if name == "" {
+			writeMessage(w, http.StatusUnprocessableEntity, "name is required")
+			return
+		}
+		if o.configByName(name) != nil {
+			writeMessage(w, http.StatusUnprocessableEntity, "Name has already been taken")
+			return
+		}
+		delete(c, "default_for_new_repos")
+		c["id"] = s.newID()
+		c["target_type"] = "organization"
+		o.configurations = append(o.configurations, c)
+		if s.apiPrefix != "" {
+			// GHES wraps the created configuration
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"value": c})
+			return
+		}
+		writeJSON(w, http.StatusCreated, c)
+	}))
+	m.HandleFunc("GET /orgs/{org}/code-security/configurations/defaults", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := []interface{}{}
+		for _, c := range o.configurations {
+			if d, ok := o.defaults[c["id"].(int)]; ok && d != "none" {
+				items = append(items, map[string]interface{}{"default_for_new_repos": d, "configuration": c})
+			}
+		}
+		writeJSON(w, http.StatusOK, items)
+	}))
+	m.HandleFunc("DELETE /orgs/{org}/code-security/configurations/detach", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		var req struct {
+			SelectedRepositoryIDs []int `json:"selected_repository_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		for _, id := range req.SelectedRepositoryIDs {
+			if repo := o.repoByID(id); repo != nil {
+				delete(o.attachments, repo.Name)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	withConfig := func(h func(http.ResponseWriter, *http.Request, *orgState, map[string]interface{})) http.HandlerFunc {
+		return s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+			id, _ := strconv.Atoi(r.PathValue("id"))
+			c := o.config(id)
+			if c == nil {
+				writeMessage(w, http.StatusNotFound, "Not Found")
+				return
+			}
+			h(w, r, o, c)
+		})
+	}
+	m.HandleFunc("GET /orgs/{org}/code-security/configurations/{id}", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		writeJSON(w, http.StatusOK, c)
+	}))
+	m.HandleFunc("PATCH /orgs/{org}/code-security/configurations/{id}", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		for k, v := range patch {
+			if k != "id" && k != "default_for_new_repos" {
+				c[k] = v
+			}
+		}
+		writeJSON(w, http.StatusOK, c)
+	}))
+	m.HandleFunc("DELETE /orgs/{org}/code-security/configurations/{id}", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		id := c["id"].(int)
+		for i, existing := range o.configurations {
+			if existing["id"] == id {
+				o.configurations = append(o.configurations[:i], o.configurations[i+1:]...)
+				break
+			}
+		}
+		for repo, a := range o.attachments {
+			if a.ConfigID == id {
+				delete(o.attachments, repo)
+			}
+		}
+		delete(o.defaults, id)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.HandleFunc("PUT /orgs/{org}/code-security/configurations/{id}/defaults", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		var req struct {
+			DefaultForNewRepos string `json:"default_for_new_repos"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		o.defaults[c["id"].(int)] = req.DefaultForNewRepos
+		writeJSON(w, http.StatusOK, map[string]interface{}{"default_for_new_repos": req.DefaultForNewRepos, "configuration": c})
+	}))
+	m.HandleFunc("GET /orgs/{org}/code-security/configurations/{id}/repositories", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		items := []interface{}{}
+		for _, repo := range o.repos {
+			if a, ok := o.attachments[repo.Name]; ok && a.ConfigID == c["id"] {
+				items = append(items, map[string]interface{}{
+					"status":     a.Status,
+					"repository": map[string]interface{}{"id": repo.ID, "name": repo.Name, "full_name": o.name + "/" + repo.Name},
+				})
+			}
+		}
+		s.paginate(w, r, items)
+	}))
+	m.HandleFunc("POST /orgs/{org}/code-security/configurations/{id}/attach", withConfig(func(w http.ResponseWriter, r *http.Request, o *orgState, c map[string]interface{}) {
+		var req struct {
+			Scope                 string `json:"scope"`
+			SelectedRepositoryIDs []int  `json:"selected_repository_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		var targets []*FakeRepo
+		switch req.Scope {
+		case "selected":
+			for _, id := range req.SelectedRepositoryIDs {
+				repo := o.repoByID(id)
+				if repo == nil {
+					writeMessage(w, http.StatusUnprocessableEntity, fmt.Sprintf("Repository %d not found", id))
+					return
+				}
+				targets = append(targets, repo)
+			}
+		case "all", "all_without_configurations", "public", "private_or_internal":
+			for _, repo := range o.repos {
+				if req.Scope == "public" && repo.Visibility != "public" || req.Scope == "private_or_internal" && repo.Visibility == "public" {
+					continue
+				}
+				if _, ok := o.attachments[repo.Name]; ok && req.Scope == "all_without_configurations" {
+					continue
+				}
+				targets = append(targets, repo)
+			}
+		default:
+			writeMessage(w, http.StatusUnprocessableEntity, "Invalid scope")
+			return
+		}
+		for _, repo := range targets {
+			o.attachments[repo.Name] = attachment{ConfigID: c["id"].(int), Status: "attached"}
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{})
+	}))
+	m.HandleFunc("GET /repos/{owner}/{repo}/code-security-configuration", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		a, ok := o.attachments[repo.Name]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": a.Status, "configuration": o.config(a.ConfigID)})
+	}))
+
+	// Code scanning
+	m.HandleFunc("GET /repos/{owner}/{repo}/code-scanning/default-setup", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		setup, ok := o.defaultSetup[repo.Name]
+		if !ok {
+			setup = map[string]interface{}{"state": "not-configured", "languages": []string{}, "query_suite": "default", "runner_type": "standard"}
+		}
+		writeJSON(w, http.StatusOK, setup)
+	}))
+	m.HandleFunc("PATCH /repos/{owner}/{repo}/code-scanning/default-setup", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		setup, ok := o.defaultSetup[repo.Name]
+		if !ok {
+			setup = map[string]interface{}{"state": "not-configured", "languages": []string{}, "query_suite": "default", "runner_type": "standard"}
+			o.defaultSetup[repo.Name] = setup
+		}
+		for k, v := range patch {
+			setup[k] = v
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"run_id": 1, "run_url": baseURL(r) + "/runs/1"})
+	}))
+	m.HandleFunc("GET /repos/{owner}/{repo}/code-scanning/analyses", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		items := []interface{}{}
+		if setup, ok := o.defaultSetup[repo.Name]; ok && setup["state"] == "configured" {
+			items = append(items, map[string]interface{}{"id": 1, "ref": "refs/heads/main", "tool": map[string]string{"name": "CodeQL"}})
+		}
+		s.paginate(w, r, items)
+	}))
+	m.HandleFunc("GET /orgs/{org}/code-scanning/alerts", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		s.paginate(w, r, filterAlerts(r, o, o.codeAlerts, map[string]func(map[string]interface{}) string{
+			"state":     str("state"),
+			"tool_name": str("tool", "name"),
+			"severity": func(a map[string]interface{}) string {
+				return firstNonEmpty(str("rule", "security_severity_level")(a), str("rule", "severity")(a))
+			},
+		}))
+	}))
+
+	// Secret scanning
+	m.HandleFunc("GET /orgs/{org}/secret-scanning/alerts", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		items := filterAlerts(r, o, o.secretAlerts, map[string]func(map[string]interface{}) string{
+			"state":       str("state"),
+			"secret_type": str("secret_type"),
+			"resolution":  str("resolution"),
+			"validity":    str("validity"),
+		})
+		if r.URL.Query().Get("hide_secret") == "true" {
+			for _, item := range items {
+				delete(item.(map[string]interface{}), "secret")
+			}
+		}
+		s.paginate(w, r, items)
+	}))
+	findSecretAlert := func(o *orgState, repo *FakeRepo, number int) map[string]interface{} {
+		for _, a := range o.secretAlerts {
+			if a["number"] == number && strings.EqualFold(fmt.Sprint(a["repo"]), repo.Name) {
+				return a
+			}
+		}
+		return nil
+	}
+	m.HandleFunc("PATCH /repos/{owner}/{repo}/secret-scanning/alerts/{number}", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		number, _ := strconv.Atoi(r.PathValue("number"))
+		a := findSecretAlert(o, repo, number)
+		if a == nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		for _, k := range []string{"state", "resolution", "resolution_comment"} {
+			if v, ok := patch[k]; ok {
+				a[k] = v
+			}
+		}
+		if a["state"] == "resolved" {
+			a["resolved_at"] = time.Now().UTC().Format(time.RFC3339)
+		}
+		writeJSON(w, http.StatusOK, filterAlerts(r, o, []map[string]interface{}{a}, nil)[0])
+	}))
+	m.HandleFunc("GET /repos/{owner}/{repo}/secret-scanning/alerts/{number}/locations", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		number, _ := strconv.Atoi(r.PathValue("number"))
+		a := findSecretAlert(o, repo, number)
+		if a == nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		items := []interface{}{}
+		if locations, ok := a["locations"].([]interface{}); ok {
+			items = locations
+		}
+		s.paginate(w, r, items)
+	}))
+	m.HandleFunc("GET /orgs/{org}/bypass-requests/secret-scanning", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		switch r.URL.Query().Get("time_period") {
+		case "", "hour", "day", "week", "month":
+		default:
+			writeMessage(w, http.StatusUnprocessableEntity, "Invalid time_period")
+			return
+		}
+		s.paginate(w, r, filterAlerts(r, o, o.bypassRequests, map[string]func(map[string]interface{}) string{
+			"request_status": str("status"),
+		}))
+	}))
+
+	// Dependabot
+	m.HandleFunc("GET /orgs/{org}/dependabot/alerts", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		s.paginate(w, r, filterAlerts(r, o, o.depAlerts, map[string]func(map[string]interface{}) string{
+			"state":     str("state"),
+			"severity":  str("security_advisory", "severity"),
+			"ecosystem": str("dependency", "package", "ecosystem"),
+		}))
+	}))
+	m.HandleFunc("GET /repos/{owner}/{repo}/dependabot/alerts", s.withRepo(func(w http.ResponseWriter, r *http.Request, o *orgState, repo *FakeRepo) {
+		var alerts []map[string]interface{}
+		for _, a := range o.depAlerts {
+			if strings.EqualFold(fmt.Sprint(a["repo"]), repo.Name) {
+				alerts = append(alerts, a)
+			}
+		}
+		s.paginate(w, r, filterAlerts(r, o, alerts, map[string]func(map[string]interface{}) string{"state": str("state")}))
+	}))
+
+	// Control endpoints for test scripts
+	m.HandleFunc("GET /_fake/requests", func(w http.ResponseWriter, r *http.Request) {
+		requests := s.requests
+		if requests == nil {
+			requests = []RecordedRequest{}
+		}
+		writeJSON(w, http.StatusOK, requests)
+	})
+	m.HandleFunc("DELETE /_fake/requests", func(w http.ResponseWriter, r *http.Request) {
+		s.requests = nil
+		w.WriteHeader(http.StatusNoContent)
+	})
+	m.HandleFunc("GET /_fake/faults", func(w http.ResponseWriter, r *http.Request) {
+		faults := s.faults
+		if faults == nil {
+			faults = []*Fault{}
+		}
+		writeJSON(w, http.StatusOK, faults)
+	})
+	m.HandleFunc("PUT /_fake/faults", func(w http.ResponseWriter, r *http.Request) {
+		var faults []*Fault
+		if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.faults = faults
+		w.WriteHeader(http.StatusNoContent)
+	})
+	m.HandleFunc("POST /_fake/reset", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.load(); err != nil {
+			writeMessage(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	m.HandleFunc("GET /_fake/state/{org}", s.withOrg(func(w http.ResponseWriter, r *http.Request, o *orgState) {
+		attachments := make(map[string]string)
+		for repo, a := range o.attachments {
+			if c := o.config(a.ConfigID); c != nil {
+				attachments[repo] = fmt.Sprint(c["name"])
+			}
+		}
+		defaults := make(map[string]string)
+		for id, d := range o.defaults {
+			if c := o.config(id); c != nil {
+				defaults[fmt.Sprint(c["name"])] = d
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"repos":                  o.repos,
+			"configurations":         o.configurations,
+			"defaults":               defaults,
+			"attachments":            attachments,
+			"default_setup":          o.defaultSetup,
+			"secret_scanning_alerts": o.secretAlerts,
+		})
+	}))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8765", "Address to listen on")
+	seedPath := flag.String("seed", "", "JSON file with the initial state (orgs, repos, configurations, alerts, faults)")
+	token := flag.String("token", "", "Only accept this token (default: any non-empty Authorization header)")
+	maxPerPage := flag.Int("max-per-page", 0, "Cap per_page to force pagination in clients (0 for GitHub's limit of 100)")
+	flag.Parse()
+
+	s := &fakeServer{seedPath: *seedPath, token: *token, maxPerPage: *maxPerPage}
+	if err := s.load(); err != nil {
+		log.Fatalf("Failed to load seed: %v", err)
+	}
+	s.routes()
+
+	orgs := make([]string, 0, len(s.orgs))
+	for _, o := range s.orgs {
+		orgs = append(orgs, o.name)
+	}
+	sort.Strings(orgs)
+	fmt.Printf("Fake GitHub API listening on http://%s (orgs: %s)\n", *addr, strings.Join(orgs, ", "))
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
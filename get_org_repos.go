@@ -8,7 +8,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,11 +22,258 @@ type RepoList struct {
 	Repositories []string `yaml:"repositories"`
 }
 
+// orgRepo holds the fields of the org repository listing used for filtering.
+type orgRepo struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Private    bool      `json:"private"`
+	Visibility string    `json:"visibility"`
+	Fork       bool      `json:"fork"`
+	Archived   bool      `json:"archived"`
+	IsTemplate bool      `json:"is_template"`
+	Language   string    `json:"language"`
+	Topics     []string  `json:"topics"`
+	PushedAt   time.Time `json:"pushed_at"`
+}
+
+// InventoryRepo is one entry of the repository inventory file.
+type InventoryRepo struct {
+	ID         int    `yaml:"id" json:"id"`
+	Name       string `yaml:"name" json:"name"`
+	Visibility string `yaml:"visibility" json:"visibility"`
+	Archived   bool   `yaml:"archived" json:"archived"`
+	Fork       bool   `yaml:"fork" json:"fork"`
+}
+
+// Inventory is the state written by -inventory and compared on the next run.
+type Inventory struct {
+	Organization string          `yaml:"organization" json:"organization"`
+	GeneratedAt  string          `yaml:"generated_at" json:"generated_at"`
+	Inventory    []InventoryRepo `yaml:"inventory" json:"inventory"`
+}
+
+type RenamedRepo struct {
+	ID      int    `yaml:"id" json:"id"`
+	OldName string `yaml:"old_name" json:"old_name"`
+	NewName string `yaml:"new_name" json:"new_name"`
+}
+
+type VisibilityChange struct {
+	ID            int    `yaml:"id" json:"id"`
+	Name          string `yaml:"name" json:"name"`
+	OldVisibility string `yaml:"old_visibility" json:"old_visibility"`
+	NewVisibility string `yaml:"new_visibility" json:"new_visibility"`
+}
+
+// InventoryChanges is the difference between two inventories, matched by repository ID.
+type InventoryChanges struct {
+	Added             []InventoryRepo    `yaml:"added" json:"added"`
+	Removed           []InventoryRepo    `yaml:"removed" json:"removed"`
+	Renamed           []RenamedRepo      `yaml:"renamed" json:"renamed"`
+	VisibilityChanged []VisibilityChange `yaml:"visibility_changed" json:"visibility_changed"`
+}
+
+func repoVisibility(r orgRepo) string {
+	if r.Visibility != "" {
+		return r.Visibility
+	}
+	if r.Private {
+		return "private"
+	}
+	return "public"
+}
+
+// loadInventory reads a previous inventory; a missing file yields an empty inventory.
+func loadInventory(path string) (*Inventory, bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Inventory{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read inventory '%s': %w", path, err)
+	}
+	var inv Inventory
+	if err := yaml.Unmarshal(data, &inv); err != nil {
+		return nil, false, fmt.Errorf("failed to parse inventory '%s': %w", path, err)
+	}
+	return &inv, true, nil
+}
+
+func diffInventory(prev, curr []InventoryRepo) InventoryChanges {
+	var changes InventoryChanges
+	prevByID := make(map[int]InventoryRepo, len(prev))
+	for _, r := range prev {
+		prevByID[r.ID] = r
+	}
+	currByID := make(map[int]struct{}, len(curr))
+	for _, r := range curr {
+		currByID[r.ID] = struct{}{}
+		old, ok := prevByID[r.ID]
+		if !ok {
+			changes.Added = append(changes.Added, r)
+			continue
+		}
+		if old.Name != r.Name {
+			changes.Renamed = append(changes.Renamed, RenamedRepo{ID: r.ID, OldName: old.Name, NewName: r.Name})
+		}
+		if old.Visibility != r.Visibility {
+			changes.VisibilityChanged = append(changes.VisibilityChanged, VisibilityChange{ID: r.ID, Name: r.Name, OldVisibility: old.Visibility, NewVisibility: r.Visibility})
+		}
+	}
+	for _, r := range prev {
+		if _, ok := currByID[r.ID]; !ok {
+			changes.Removed = append(changes.Removed, r)
+		}
+	}
+	sort.Slice(changes.Added, func(i, j int) bool { return changes.Added[i].Name < changes.Added[j].Name })
+	sort.Slice(changes.Removed, func(i, j int) bool { return changes.Removed[i].Name < changes.Removed[j].Name })
+	return changes
+}
+
+func printInventoryChanges(c InventoryChanges) {
+	fmt.Printf("Inventory changes: %d added, %d removed, %d renamed, %d visibility changed\n",
+		len(c.Added), len(c.Removed), len(c.Renamed), len(c.VisibilityChanged))
+	for _, r := range c.Added {
+		fmt.Printf("  + %s (ID: %d, %s)\n", r.Name, r.ID, r.Visibility)
+	}
+	for _, r := range c.Removed {
+		fmt.Printf("  - %s (ID: %d, %s)\n", r.Name, r.ID, r.Visibility)
+	}
+	for _, r := range c.Renamed {
+		fmt.Printf("  ~ %s -> %s (ID: %d)\n", r.OldName, r.NewName, r.ID)
+	}
+	for _, r := range c.VisibilityChanged {
+		fmt.Printf("  ! %s: %s -> %s (ID: %d)\n", r.Name, r.OldVisibility, r.NewVisibility, r.ID)
+	}
+}
+
+// matchType applies -type to a repository from a type=all listing.
+func matchType(r orgRepo, repoType string) bool {
+	switch repoType {
+	case "public", "private", "internal":
+		return repoVisibility(r) == repoType
+	case "forks":
+		return r.Fork
+	case "sources":
+		return !r.Fork
+	default:
+		return true
+	}
+}
+
+// repoFilter is the set of selection criteria applied while listing.
+type repoFilter struct {
+	archived     string
+	templates    string
+	pushedWithin time.Duration
+	topics       []string
+	languages    []string
+	nameGlob     string
+	nameRegex    *regexp.Regexp
+}
+
+// parseAge accepts a Go duration ("720h") or a number of days ("90d").
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid age '%s'", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age '%s': %w", s, err)
+	}
+	return d, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// matchTristate checks a boolean attribute against include/exclude/only.
+func matchTristate(mode string, value bool) bool {
+	switch mode {
+	case "exclude":
+		return !value
+	case "only":
+		return value
+	default:
+		return true
+	}
+}
+
+func (f repoFilter) match(r orgRepo, now time.Time) bool {
+	if !matchTristate(f.archived, r.Archived) || !matchTristate(f.templates, r.IsTemplate) {
+		return false
+	}
+	if f.pushedWithin > 0 && (r.PushedAt.IsZero() || now.Sub(r.PushedAt) > f.pushedWithin) {
+		return false
+	}
+	if len(f.topics) > 0 {
+		found := false
+		for _, t := range r.Topics {
+			for _, want := range f.topics {
+				if strings.ToLower(t) == want {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.languages) > 0 {
+		found := false
+		for _, want := range f.languages {
+			if strings.ToLower(r.Language) == want {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.nameGlob != "" {
+		if ok, _ := filepath.Match(f.nameGlob, r.Name); !ok {
+			return false
+		}
+	}
+	if f.nameRegex != nil && !f.nameRegex.MatchString(r.Name) {
+		return false
+	}
+	return true
+}
+
 func main() {
 	tokenFlag := flag.String("token", "", "GitHub API token (or set GITHUB_TOKEN_ORG / GITHUB_TOKEN env var)")
 	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
 	output := flag.String("output", "repos.yaml", "Output YAML file")
 	ghesURL := flag.String("ghes-url", "", "Base URL for GHES api (ignored for GHEC)")
+	repoType := flag.String("type", "all", "Repository type: all, public, private, internal, forks, sources, member")
+	archived := flag.String("archived", "include", "Archived repositories: include, exclude or only")
+	templates := flag.String("templates", "include", "Template repositories: include, exclude or only")
+	pushedWithin := flag.String("pushed-within", "", "Only repositories pushed within this age (e.g. 90d or 720h)")
+	topic := flag.String("topic", "", "Comma-separated topics, repository must have at least one")
+	language := flag.String("language", "", "Comma-separated primary languages (e.g. go,java)")
+	nameGlob := flag.String("name", "", "Glob pattern on repository name (e.g. 'svc-*')")
+	nameRegex := flag.String("name-regex", "", "Regular expression on repository name")
+	format := flag.String("format", "yaml", "Output format: yaml or txt (one name per line, for add_repo_to_config -repo-file)")
+	inventoryPath := flag.String("inventory", "", "Inventory file to compare against and then update (e.g. /workspace/inventory.yaml)")
+	changesPath := flag.String("changes", "", "Write the added/removed/renamed/visibility changes as YAML to this file (requires -inventory)")
+	addedOutput := flag.String("added-output", "", "Write names of newly added repositories that pass the filters, one per line, to this file (requires -inventory)")
 	flag.Parse()
 
 	// GHES_URL env var fallback
@@ -43,7 +295,62 @@ func main() {
 		os.Exit(1)
 	}
 
+	switch *repoType {
+	case "all", "public", "private", "internal", "forks", "sources", "member":
+	default:
+		log.Fatalf("Invalid -type '%s': must be all, public, private, internal, forks, sources or member", *repoType)
+	}
+	for name, v := range map[string]string{"archived": *archived, "templates": *templates} {
+		if v != "include" && v != "exclude" && v != "only" {
+			log.Fatalf("Invalid -%s '%s': must be include, exclude or only", name, v)
+		}
+	}
+	if *format != "yaml" && *format != "txt" {
+		log.Fatalf("Invalid -format '%s': must be yaml or txt", *format)
+	}
+	if *inventoryPath == "" && (*changesPath != "" || *addedOutput != "") {
+		log.Fatal("-changes and -added-output require -inventory")
+	}
+	age, err := parseAge(*pushedWithin)
+	if err != nil {
+		log.Fatalf("Invalid -pushed-within: %v", err)
+	}
+	if *nameGlob != "" {
+		if _, err := filepath.Match(*nameGlob, ""); err != nil {
+			log.Fatalf("Invalid -name pattern '%s': %v", *nameGlob, err)
+		}
+	}
+	filter := repoFilter{
+		archived:     *archived,
+		templates:    *templates,
+		pushedWithin: age,
+		topics:       splitList(*topic),
+		languages:    splitList(*language),
+		nameGlob:     *nameGlob,
+	}
+	if *nameRegex != "" {
+		re, err := regexp.Compile(*nameRegex)
+		if err != nil {
+			log.Fatalf("Invalid -name-regex: %v", err)
+		}
+		filter.nameRegex = re
+	}
+
+	// The inventory lists every repository of the organization, so archiving a repository or running with other
+	// filters than last time does not show up as repositories added or removed. -type is then applied locally.
+	listType := *repoType
+	if *inventoryPath != "" {
+		if *repoType == "member" {
+			log.Fatal("-type member cannot be combined with -inventory")
+		}
+		listType = "all"
+	}
+
 	var allRepos []string
+	var inventory []InventoryRepo
+	selected := make(map[int]bool)
+	skipped := 0
+	now := time.Now()
 	page := 1
 	perPage := 100
 
@@ -52,10 +359,10 @@ func main() {
 		var url string
 		switch githubEndpoint {
 		case "GHEC":
-			url = fmt.Sprintf("https://api.github.com/orgs/%s/repos?per_page=%d&page=%d", *org, perPage, page)
+			url = fmt.Sprintf("https://api.github.com/orgs/%s/repos?type=%s&per_page=%d&page=%d", *org, listType, perPage, page)
 		case "GHES":
 			if *ghesURL == "" { log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES") }
-			url = fmt.Sprintf("%s/orgs/%s/repos?per_page=%d&page=%d", *ghesURL, *org, perPage, page)
+			url = fmt.Sprintf("%s/orgs/%s/repos?type=%s&per_page=%d&page=%d", *ghesURL, *org, listType, perPage, page)
 		default:
 			log.Fatalf("Unknown GitHub endpoint: %s", githubEndpoint)
 		}
@@ -79,9 +386,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		var repos []struct {
-			Name string `json:"name"`
-		}
+		var repos []orgRepo
 		if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
 			log.Fatalf("Failed to decode response: %v", err)
 		}
@@ -91,21 +396,95 @@ func main() {
 		}
 
 		for _, repo := range repos {
+			inventory = append(inventory, InventoryRepo{
+				ID:         repo.ID,
+				Name:       repo.Name,
+				Visibility: repoVisibility(repo),
+				Archived:   repo.Archived,
+				Fork:       repo.Fork,
+			})
+			if !matchType(repo, *repoType) || !filter.match(repo, now) {
+				skipped++
+				continue
+			}
 			allRepos = append(allRepos, repo.Name)
+			selected[repo.ID] = true
 		}
-		if len(repos) < perPage {
+		// A short page is the last one unless the server caps per_page and still links a next page
+		if len(repos) < perPage && !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
 			break
 		}
 		page++
 	}
 
-	outputData := RepoList{Repositories: allRepos}
-	outBytes, err := yaml.Marshal(outputData)
-	if err != nil {
-		log.Fatalf("Failed to marshal YAML: %v", err)
+	var outBytes []byte
+	if *format == "txt" {
+		outBytes = []byte(strings.Join(allRepos, "\n") + "\n")
+	} else {
+		outputData := RepoList{Repositories: allRepos}
+		outBytes, err = yaml.Marshal(outputData)
+		if err != nil {
+			log.Fatalf("Failed to marshal YAML: %v", err)
+		}
 	}
 	if err := ioutil.WriteFile(*output, outBytes, 0644); err != nil {
-		log.Fatalf("Failed to write YAML file: %v", err)
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+	fmt.Printf("Wrote %d repositories to %s (%d filtered out)\n", len(allRepos), *output, skipped)
+
+	if *inventoryPath != "" {
+		prev, existed, err := loadInventory(*inventoryPath)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if !existed {
+			fmt.Printf("No previous inventory at %s, every repository is reported as added\n", *inventoryPath)
+		} else if prev.Organization != "" && prev.Organization != *org {
+			log.Fatalf("Inventory %s belongs to organization '%s', not '%s'", *inventoryPath, prev.Organization, *org)
+		}
+		changes := diffInventory(prev.Inventory, inventory)
+		printInventoryChanges(changes)
+
+		if *changesPath != "" {
+			changesBytes, err := yaml.Marshal(changes)
+			if err != nil {
+				log.Fatalf("Failed to marshal changes YAML: %v", err)
+			}
+			if err := ioutil.WriteFile(*changesPath, changesBytes, 0644); err != nil {
+				log.Fatalf("Failed to write changes file: %v", err)
+			}
+			fmt.Printf("Wrote inventory changes to %s\n", *changesPath)
+		}
+		if *addedOutput != "" {
+			// Only the repositories the filters select, so a job attaching them skips what the run excluded
+			var added []string
+			for _, r := range changes.Added {
+				if selected[r.ID] {
+					added = append(added, r.Name)
+				}
+			}
+			content := strings.Join(added, "\n")
+			if content != "" {
+				content += "\n"
+			}
+			if err := ioutil.WriteFile(*addedOutput, []byte(content), 0644); err != nil {
+				log.Fatalf("Failed to write added repositories file: %v", err)
+			}
+			fmt.Printf("Wrote %d added repositories to %s (%d filtered out)\n", len(added), *addedOutput, len(changes.Added)-len(added))
+		}
+
+		next := Inventory{
+			Organization: *org,
+			GeneratedAt:  now.UTC().Format(time.RFC3339),
+			Inventory:    inventory,
+		}
+		invBytes, err := yaml.Marshal(next)
+		if err != nil {
+			log.Fatalf("Failed to marshal inventory YAML: %v", err)
+		}
+		if err := ioutil.WriteFile(*inventoryPath, invBytes, 0644); err != nil {
+			log.Fatalf("Failed to write inventory file: %v", err)
+		}
+		fmt.Printf("Updated inventory %s (%d repositories)\n", *inventoryPath, len(inventory))
 	}
-	fmt.Printf("Wrote %d repositories to %s\n", len(allRepos), *output)
 }
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture is one sanitized request/response pair, stored as a JSON file.
+type Fixture struct {
+	Upstream    string            `json:"upstream"`
+	RecordedAt  time.Time         `json:"recorded_at"`
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Query       string            `json:"query,omitempty"`
+	RequestBody json.RawMessage   `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        json.RawMessage   `json:"body,omitempty"`
+	BodyText    string            `json:"body_text,omitempty"`
+	file        string
+}
+
+// baseURLPlaceholder replaces the upstream API base in recorded URLs.
+const baseURLPlaceholder = "{{base_url}}"
+
+// hostPlaceholder replaces the GHES host wherever else it appears, e.g. in html_url, git_url or ssh_url.
+const hostPlaceholder = "ghes.example.com"
+
+// Response headers worth keeping: everything else is either noise or identifies the instance.
+var keptHeaders = []string{"Content-Type", "Link", "Location", "Retry-After", "X-Github-Enterprise-Version", "X-Github-Media-Type", "X-Ratelimit-Remaining"}
+
+var (
+	tokenPattern = regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{20,}\b|\bgithub_pat_[A-Za-z0-9_]{20,}\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+type sanitizer struct {
+	upstream   string
+	hosts      []string
+	redactKeys map[string]bool
+}
+
+func (s *sanitizer) text(v string) string {
+	v = strings.ReplaceAll(v, s.upstream, baseURLPlaceholder)
+	for _, h := range s.hosts {
+		v = strings.ReplaceAll(v, h, hostPlaceholder)
+	}
+	v = tokenPattern.ReplaceAllString(v, "REDACTED")
+	return emailPattern.ReplaceAllString(v, "user@example.com")
+}
+
+func (s *sanitizer) value(key string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = s.value(k, child)
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = s.value(key, child)
+		}
+		return t
+	case string:
+		if s.redactKeys[strings.ToLower(key)] && t != "" {
+			return "REDACTED"
+		}
+		return s.text(t)
+	}
+	return v
+}
+
+// body returns the sanitized body as JSON, or as text when it is not JSON.
+func (s *sanitizer) body(raw []byte) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, s.text(string(raw))
+	}
+	out, err := marshalFixture(s.value("", v))
+	if err != nil {
+		return nil, s.text(string(raw))
+	}
+	return out, ""
+}
+
+// marshalFixture indents without escaping &, < and >, which keeps Link headers and queries readable.
+func marshalFixture(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// canonicalPath drops the /api/v3 prefix so GHES and GHEC recordings match the same requests.
+func canonicalPath(p string) string {
+	p = strings.TrimPrefix(p, "/api/v3")
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// fixtureKey identifies a request independently of query parameter order.
+func fixtureKey(method, p, rawQuery string) string {
+	q, _ := url.ParseQuery(rawQuery)
+	return strings.ToUpper(method) + " " + canonicalPath(p) + "?" + q.Encode()
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type recorder struct {
+	upstream *url.URL
+	ghec     bool
+	dir      string
+	clean    *sanitizer
+	client   *http.Client
+	mu       sync.Mutex
+	next     int
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	// The commands use GHES_URL as the API base, so the path is forwarded below -upstream as is
+	target := *rec.upstream
+	target.Path = strings.TrimSuffix(rec.upstream.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequest(r.Method, target.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for _, h := range []string{"Accept", "Authorization", "Content-Type", "X-GitHub-Api-Version"} {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	resp, err := rec.client.Do(req)
+	if err != nil {
+		log.Printf("❌ %s %s: %v", r.Method, target.Path, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// The client gets the real response with upstream URLs pointing back at the recorder
+	local := "http://" + r.Host
+	upstreamBase := rec.clean.upstream
+	for _, h := range keptHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, strings.ReplaceAll(v, upstreamBase, local))
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(bytes.ReplaceAll(respBody, []byte(upstreamBase), []byte(local)))
+
+	f := Fixture{
+		Upstream:   "GHES",
+		RecordedAt: time.Now().UTC().Truncate(time.Second),
+		Method:     r.Method,
+		Path:       canonicalPath(r.URL.Path),
+		Query:      rec.clean.text(r.URL.RawQuery),
+		Status:     resp.StatusCode,
+		Headers:    make(map[string]string),
+	}
+	if rec.ghec {
+		f.Upstream = "GHEC"
+	}
+	f.RequestBody, _ = rec.clean.body(reqBody)
+	f.Body, f.BodyText = rec.clean.body(respBody)
+	for _, h := range keptHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			f.Headers[h] = rec.clean.text(v)
+		}
+	}
+	if err := rec.save(&f); err != nil {
+		log.Printf("❌ Failed to save fixture for %s %s: %v", r.Method, r.URL.Path, err)
+		return
+	}
+	fmt.Printf("📼 %d %s %s -> %s\n", f.Status, f.Method, f.Path, filepath.Base(f.file))
+}
+
+func (rec *recorder) save(f *Fixture) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.next++
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(f.Path, "_"), "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	f.file = filepath.Join(rec.dir, fmt.Sprintf("%04d-%s-%s.json", rec.next, f.Method, name))
+	data, err := marshalFixture(f)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.file, append(data, '\n'), 0644)
+}
+
+// loadFixtures reads every fixture in dir in file name order, which is the recording order.
+func loadFixtures(dir string) ([]*Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var fixtures []*Fixture
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if f.Method == "" || f.Path == "" {
+			return nil, fmt.Errorf("%s: missing method or path", file)
+		}
+		f.file = file
+		fixtures = append(fixtures, &f)
+	}
+	return fixtures, nil
+}
+
+type replayer struct {
+	mu     sync.Mutex
+	queues map[string][]*Fixture
+	served map[string]int
+	misses []string
+}
+
+// ServeHTTP answers with the recorded fixtures for a request in order, repeating the last one.
+func (rp *replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if r.URL.Path == "/_fixtures/misses" {
+		writeFixtureJSON(w, http.StatusOK, rp.misses)
+		return
+	}
+	key := fixtureKey(r.Method, r.URL.Path, r.URL.RawQuery)
+	queue := rp.queues[key]
+	if len(queue) == 0 {
+		rp.misses = append(rp.misses, key)
+		log.Printf("⚠️  No fixture for %s", key)
+		writeFixtureJSON(w, http.StatusNotFound, map[string]string{"message": "No fixture recorded for " + key})
+		return
+	}
+	i := rp.served[key]
+	if i >= len(queue) {
+		i = len(queue) - 1
+	}
+	rp.served[key]++
+	f := queue[i]
+
+	base := "http://" + r.Host
+	for h, v := range f.Headers {
+		w.Header().Set(h, strings.ReplaceAll(v, baseURLPlaceholder, base))
+	}
+	body := []byte(f.BodyText)
+	if len(f.Body) > 0 {
+		body = f.Body
+	}
+	body = bytes.ReplaceAll(body, []byte(baseURLPlaceholder), []byte(base))
+	w.WriteHeader(f.Status)
+	w.Write(body)
+	fmt.Printf("▶️  %d %s %s <- %s\n", f.Status, r.Method, r.URL.Path, filepath.Base(f.file))
+}
+
+func writeFixtureJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func main() {
+	mode := flag.String("mode", "", "record or replay")
+	dir := flag.String("dir", "", "Fixture directory (e.g. fixtures/ghes-3.14-create-config)")
+	addr := flag.String("addr", "127.0.0.1:8767", "Address to listen on")
+	upstream := flag.String("upstream", "", "record: GitHub API base to forward to, e.g. https://ghes.example.com/api/v3 or https://api.github.com (default: GHES_URL)")
+	redact := flag.String("redact-keys", "", "record: extra comma-separated JSON keys whose string values are replaced with REDACTED")
+	flag.Parse()
+
+	if *dir == "" || (*mode != "record" && *mode != "replay") {
+		log.Fatal("Usage: go run http_fixtures.go -mode record|replay -dir <fixture-dir> [-upstream https://ghes.example.com/api/v3] [-addr 127.0.0.1:8767]")
+	}
+
+	var handler http.Handler
+	switch *mode {
+	case "record":
+		if *upstream == "" {
+			*upstream = os.Getenv("GHES_URL")
+		}
+		u, err := url.Parse(strings.TrimSuffix(*upstream, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			log.Fatalf("Invalid -upstream '%s': use a URL like https://ghes.example.com/api/v3", *upstream)
+		}
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			log.Fatalf("Failed to create %s: %v", *dir, err)
+		}
+		existing, err := filepath.Glob(filepath.Join(*dir, "*.json"))
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *dir, err)
+		}
+		keys := map[string]bool{"secret": true, "token": true, "password": true, "private_key": true, "client_secret": true, "access_token": true}
+		for _, k := range strings.Split(*redact, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys[strings.ToLower(k)] = true
+			}
+		}
+		ghec := strings.EqualFold(u.Host, "api.github.com")
+		clean := &sanitizer{upstream: u.String(), redactKeys: keys}
+		if !ghec {
+			// Web and clone URLs carry the host without the API path, ssh_url without the port
+			clean.hosts = []string{u.Host}
+			if u.Hostname() != u.Host {
+				clean.hosts = append(clean.hosts, u.Hostname())
+			}
+		}
+		handler = &recorder{
+			upstream: u,
+			ghec:     ghec,
+			dir:      *dir,
+			clean:    clean,
+			client:   &http.Client{Timeout: 60 * time.Second},
+			next:     len(existing),
+		}
+		fmt.Printf("Recording %s into %s\n", u.Host, *dir)
+	case "replay":
+		fixtures, err := loadFixtures(*dir)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		if len(fixtures) == 0 {
+			log.Fatalf("No fixtures in %s", *dir)
+		}
+		rp := &replayer{queues: make(map[string][]*Fixture), served: make(map[string]int)}
+		for _, f := range fixtures {
+			key := fixtureKey(f.Method, f.Path, f.Query)
+			rp.queues[key] = append(rp.queues[key], f)
+		}
+		handler = rp
+		fmt.Printf("Replaying %d fixtures from %s\n", len(fixtures), *dir)
+	}
+
+	fmt.Printf("Point the commands at it with GITHUB_ENDPOINT=GHES GHES_URL=http://%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
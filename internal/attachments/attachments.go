@@ -0,0 +1,111 @@
+// Package attachments snapshots which code security configuration each repository is attached to, so that
+// add_repo_to_config.go can record the state before an attach and rollback.go can restore it.
+package attachments
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+// Snapshot records the configuration each repository was attached to before add_repo_to_config changed it.
+type Snapshot struct {
+	Organization string       `json:"organization"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Config       string       `json:"config"`
+	ConfigID     int          `json:"config_id"`
+	Repositories []Repository `json:"repositories"`
+}
+
+// Repository is one repository in a snapshot. ConfigurationID is 0 when no configuration was attached.
+type Repository struct {
+	Name            string `json:"name"`
+	ID              int    `json:"id"`
+	ConfigurationID int    `json:"configuration_id,omitempty"`
+	Configuration   string `json:"configuration,omitempty"`
+	Status          string `json:"status,omitempty"`
+}
+
+// Current maps repository IDs to their configuration by listing the repositories of every configuration.
+// That is one paginated call per configuration instead of one call per repository.
+func Current(client *http.Client, apiBase, org, token string, configs []map[string]interface{}) (map[int]Repository, error) {
+	attached := make(map[int]Repository)
+	for _, cfg := range configs {
+		id, _ := cfg["id"].(float64)
+		name, _ := cfg["name"].(string)
+		next := fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/repositories?per_page=100", apiBase, org, int(id))
+		for next != "" {
+			req, err := ghapi.NewRequest("GET", next, token, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return nil, fmt.Errorf("API error listing repositories of configuration '%s': %s\n%s", name, resp.Status, string(body))
+			}
+			var page []struct {
+				Status     string `json:"status"`
+				Repository struct {
+					ID   int    `json:"id"`
+					Name string `json:"name"`
+				} `json:"repository"`
+			}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return nil, fmt.Errorf("failed to parse repositories JSON: %w", err)
+			}
+			for _, item := range page {
+				switch item.Status {
+				case "detached", "removed", "removed_by_enterprise":
+					continue
+				}
+				attached[item.Repository.ID] = Repository{
+					Name:            item.Repository.Name,
+					ID:              item.Repository.ID,
+					ConfigurationID: int(id),
+					Configuration:   name,
+					Status:          item.Status,
+				}
+			}
+			next = ghapi.NextPageURL(resp.Header.Get("Link"))
+		}
+	}
+	return attached, nil
+}
+
+// WriteSnapshot saves a snapshot as indented JSON, creating the directory when needed.
+func WriteSnapshot(path string, snap Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadSnapshot loads a snapshot written by WriteSnapshot.
+func ReadSnapshot(path string) (Snapshot, error) {
+	var snap Snapshot
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,116 @@
+// Package auditlog writes the JSONL audit log that every command changing GitHub state appends to.
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Timestamp     time.Time   `json:"timestamp"`
+	Actor         string      `json:"actor"`
+	LocalUser     string      `json:"local_user,omitempty"`
+	Command       string      `json:"command"`
+	Action        string      `json:"action"`
+	Org           string      `json:"org"`
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Config        string      `json:"config,omitempty"`
+	ConfigID      int         `json:"config_id,omitempty"`
+	Repositories  []string    `json:"repositories,omitempty"`
+	RepositoryIDs []int       `json:"repository_ids,omitempty"`
+	Before        interface{} `json:"before,omitempty"`
+	After         interface{} `json:"after,omitempty"`
+	Status        int         `json:"status,omitempty"`
+	Result        string      `json:"result"`
+	Error         string      `json:"error,omitempty"`
+	// Set when policy violations were overridden with -override-policy
+	PolicyViolations []string `json:"policy_violations,omitempty"`
+	OverrideReason   string   `json:"override_reason,omitempty"`
+}
+
+// Path resolves the log path from the command flag, then AUDIT_LOG, then the workspace default.
+func Path(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv("AUDIT_LOG"); env != "" {
+		return env
+	}
+	return "workspace/audit.jsonl"
+}
+
+// Actor returns the login of the token owner, or "unknown" when the token cannot read /user (e.g. app tokens).
+func Actor(client *http.Client, apiBase, token string) string {
+	req, err := ghapi.NewRequest("GET", apiBase+"/user", token, nil)
+	if err != nil {
+		return "unknown"
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "unknown"
+	}
+	defer resp.Body.Close()
+	var user struct {
+		Login string `json:"login"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&user) != nil || user.Login == "" {
+		return "unknown"
+	}
+	return user.Login
+}
+
+// Append appends e to the audit log at path. The change already happened, so a write failure is only reported.
+func Append(path string, e Entry) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.LocalUser == "" {
+		e.LocalUser = os.Getenv("USER")
+	}
+	if e.Result == "" {
+		e.Result = "success"
+		if e.Error != "" {
+			e.Result = "failure"
+		}
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		if dir := filepath.Dir(path); dir != "." {
+			err = os.MkdirAll(dir, 0755)
+		}
+	}
+	if err == nil {
+		var f *os.File
+		f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.Write(append(line, '\n'))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to write audit log %s: %v\n", path, err)
+	}
+}
+
+// Body decodes a JSON response body for the audit log, keeping it as text when it is not JSON.
+func Body(body []byte) interface{} {
+	var v interface{}
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+	return v
+}
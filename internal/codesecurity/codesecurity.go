@@ -0,0 +1,111 @@
+// Package codesecurity holds the code security configuration types and helpers shared by the commands that read,
+// copy, export and update configurations.
+package codesecurity
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Reviewer represents a reviewer for delegated bypass
+type Reviewer struct {
+	ReviewerID   int    `yaml:"reviewer_id" json:"reviewer_id"`
+	ReviewerType string `yaml:"reviewer_type" json:"reviewer_type"`
+}
+
+// SecretScanningDelegatedBypassOptions represents the options for delegated bypass
+type SecretScanningDelegatedBypassOptions struct {
+	Reviewers []Reviewer `yaml:"reviewers,omitempty" json:"reviewers"`
+}
+
+type DependencyGraphAutosubmitActionOptions struct {
+	LabeledRunners bool `yaml:"labeled_runners" json:"labeled_runners"`
+}
+
+type CodeScanningDefaultSetupOptions struct {
+	RunnerType  string      `yaml:"runner_type,omitempty" json:"runner_type"`
+	RunnerLabel interface{} `yaml:"runner_label,omitempty" json:"runner_label"`
+}
+
+// Config is a configuration as GitHub returns it and as the YAML files describe it, in the field order of
+// template/sample_org_config.yaml. Fields that are not set are not written.
+type Config struct {
+	ID                                     int                                     `yaml:"-" json:"id,omitempty"`
+	TargetType                             string                                  `yaml:"-" json:"target_type,omitempty"`
+	Name                                   string                                  `yaml:"name" json:"name"`
+	Description                            string                                  `yaml:"description" json:"description"`
+	AdvancedSecurity                       string                                  `yaml:"advanced_security,omitempty" json:"advanced_security"`
+	DependencyGraph                        string                                  `yaml:"dependency_graph,omitempty" json:"dependency_graph"`
+	DependencyGraphAutosubmitAction        string                                  `yaml:"dependency_graph_autosubmit_action,omitempty" json:"dependency_graph_autosubmit_action"`
+	DependencyGraphAutosubmitActionOptions *DependencyGraphAutosubmitActionOptions `yaml:"dependency_graph_autosubmit_action_options,omitempty" json:"dependency_graph_autosubmit_action_options,omitempty"`
+	DependabotAlerts                       string                                  `yaml:"dependabot_alerts,omitempty" json:"dependabot_alerts"`
+	DependabotSecurityUpdates              string                                  `yaml:"dependabot_security_updates,omitempty" json:"dependabot_security_updates"`
+	PrivateVulnerabilityReporting          string                                  `yaml:"private_vulnerability_reporting,omitempty" json:"private_vulnerability_reporting"`
+	SecretScanning                         string                                  `yaml:"secret_scanning,omitempty" json:"secret_scanning"`
+	SecretScanningValidityChecks           string                                  `yaml:"secret_scanning_validity_checks,omitempty" json:"secret_scanning_validity_checks"`
+	SecretScanningNonProviderPatterns      string                                  `yaml:"secret_scanning_non_provider_patterns,omitempty" json:"secret_scanning_non_provider_patterns"`
+	SecretScanningGenericSecrets           string                                  `yaml:"secret_scanning_generic_secrets,omitempty" json:"secret_scanning_generic_secrets"`
+	SecretScanningPushProtection           string                                  `yaml:"secret_scanning_push_protection,omitempty" json:"secret_scanning_push_protection"`
+	SecretScanningDelegatedBypass          string                                  `yaml:"secret_scanning_delegated_bypass,omitempty" json:"secret_scanning_delegated_bypass"`
+	SecretScanningDelegatedBypassOptions   *SecretScanningDelegatedBypassOptions   `yaml:"secret_scanning_delegated_bypass_options,omitempty" json:"secret_scanning_delegated_bypass_options,omitempty"`
+	SecretScanningDelegatedAlertDismissal  string                                  `yaml:"secret_scanning_delegated_alert_dismissal,omitempty" json:"secret_scanning_delegated_alert_dismissal"`
+	CodeScanningDefaultSetup               string                                  `yaml:"code_scanning_default_setup,omitempty" json:"code_scanning_default_setup"`
+	CodeScanningDefaultSetupOptions        *CodeScanningDefaultSetupOptions        `yaml:"code_scanning_default_setup_options,omitempty" json:"code_scanning_default_setup_options,omitempty"`
+	// Set through the /defaults endpoint, not part of the configuration body
+	DefaultForNewRepos string `yaml:"default_for_new_repos,omitempty" json:"-"`
+	Enforcement        string `yaml:"enforcement,omitempty" json:"enforcement"`
+}
+
+// Default is one entry of GET /orgs/{org}/code-security/configurations/defaults.
+type Default struct {
+	DefaultForNewRepos string `json:"default_for_new_repos"`
+	Configuration      struct {
+		ID int `json:"id"`
+	} `json:"configuration"`
+}
+
+// unsafeNameChars are replaced when a configuration name is used as a file name.
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileName turns a configuration name into a safe file or directory name.
+func FileName(name string) string {
+	return unsafeNameChars.ReplaceAllString(name, "_")
+}
+
+// Find returns the configuration with the given name, or nil.
+func Find(configs []Config, name string) *Config {
+	for i := range configs {
+		if configs[i].Name == name {
+			return &configs[i]
+		}
+	}
+	return nil
+}
+
+// Fields returns the fields of a configuration that are set, as the JSON the API accepts.
+func Fields(cfg Config) map[string]interface{} {
+	cfg.ID, cfg.TargetType = 0, ""
+	data, _ := json.Marshal(cfg)
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return PruneEmpty(m)
+}
+
+// PruneEmpty drops empty strings, nulls and empty objects so only the values that are set are compared and sent.
+func PruneEmpty(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		switch t := v.(type) {
+		case nil:
+			delete(m, k)
+		case string:
+			if t == "" {
+				delete(m, k)
+			}
+		case map[string]interface{}:
+			if len(PruneEmpty(t)) == 0 {
+				delete(m, k)
+			}
+		}
+	}
+	return m
+}
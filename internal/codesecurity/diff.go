@@ -0,0 +1,228 @@
+package codesecurity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change is one field added, removed or changed by an update. Path holds the field names from the top level
+// down, with list items as "[i]".
+type Change struct {
+	Op   string
+	Path []string
+	Old  interface{}
+	New  interface{}
+}
+
+// Field returns the path as shown in text and markdown, e.g. secret_scanning_delegated_bypass_options.reviewers[0].reviewer_id.
+func (c Change) Field() string {
+	var b strings.Builder
+	for i, p := range c.Path {
+		if i > 0 && !strings.HasPrefix(p, "[") {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// Pointer returns the path as a JSON Pointer (RFC 6901) for JSON patch output.
+func (c Change) Pointer() string {
+	var b strings.Builder
+	for _, p := range c.Path {
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "["), "]")
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(p))
+	}
+	return b.String()
+}
+
+// Diff compares the fields desired sets with current, including nested options, list items such as reviewers and
+// default_for_new_repos. Top-level fields desired leaves out are skipped, since the update does not send them and
+// GitHub keeps them as they are; a field that is set is compared whole, so a reviewer dropped from the list shows
+// as removed. Changes come out sorted by field.
+func Diff(current, desired Config) []Change {
+	a, b := Fields(current), Fields(desired)
+	if current.DefaultForNewRepos != "" {
+		a["default_for_new_repos"] = current.DefaultForNewRepos
+	}
+	if desired.DefaultForNewRepos != "" {
+		b["default_for_new_repos"] = desired.DefaultForNewRepos
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			delete(a, k)
+		}
+	}
+	var changes []Change
+	diffValues(nil, a, b, &changes)
+	return changes
+}
+
+// ChangedFields returns the top-level fields the changes touch, sorted.
+func ChangedFields(changes []Change) []string {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, c := range changes {
+		if !seen[c.Path[0]] {
+			seen[c.Path[0]] = true
+			fields = append(fields, c.Path[0])
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func diffValues(path []string, a, b interface{}, changes *[]Change) {
+	child := func(segment string) []string {
+		return append(append([]string{}, path...), segment)
+	}
+	aMap, aIsMap := a.(map[string]interface{})
+	bMap, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := make([]string, 0, len(aMap)+len(bMap))
+		for k := range aMap {
+			keys = append(keys, k)
+		}
+		for k := range bMap {
+			if _, ok := aMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			aVal, inA := aMap[k]
+			bVal, inB := bMap[k]
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Op: "add", Path: child(k), New: bVal})
+			case !inB:
+				*changes = append(*changes, Change{Op: "remove", Path: child(k), Old: aVal})
+			default:
+				diffValues(child(k), aVal, bVal, changes)
+			}
+		}
+		return
+	}
+	aList, aIsList := a.([]interface{})
+	bList, bIsList := b.([]interface{})
+	if aIsList && bIsList {
+		for i := 0; i < len(aList) || i < len(bList); i++ {
+			p := child("[" + strconv.Itoa(i) + "]")
+			switch {
+			case i >= len(aList):
+				*changes = append(*changes, Change{Op: "add", Path: p, New: bList[i]})
+			case i >= len(bList):
+				*changes = append(*changes, Change{Op: "remove", Path: p, Old: aList[i]})
+			default:
+				diffValues(p, aList[i], bList[i], changes)
+			}
+		}
+		return
+	}
+	if diffValue(a) != diffValue(b) {
+		*changes = append(*changes, Change{Op: "change", Path: path, Old: a, New: b})
+	}
+}
+
+// diffValue formats a value for display: strings as they are, anything else as compact JSON.
+func diffValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// UseColor reports whether the text diff on stdout is colored; auto means stdout is a terminal and NO_COLOR is not set.
+func UseColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// WriteDiff renders changes as unified text, a JSON patch (RFC 6902) or a markdown table.
+func WriteDiff(w io.Writer, format string, changes []Change, from, to string, colored bool) error {
+	switch format {
+	case "json":
+		type patchOp struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value,omitempty"`
+		}
+		ops := []patchOp{}
+		for _, c := range changes {
+			switch c.Op {
+			case "add":
+				ops = append(ops, patchOp{Op: "add", Path: c.Pointer(), Value: c.New})
+			case "remove":
+				ops = append(ops, patchOp{Op: "remove", Path: c.Pointer()})
+			default:
+				ops = append(ops, patchOp{Op: "replace", Path: c.Pointer(), Value: c.New})
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(ops)
+	case "markdown":
+		cell := func(v interface{}, set bool) string {
+			if !set {
+				return ""
+			}
+			return "`" + strings.NewReplacer("|", "\\|", "`", "'", "\n", " ").Replace(diffValue(v)) + "`"
+		}
+		fmt.Fprintf(w, "#### Code security configuration changes\n\n%s → %s\n\n", from, to)
+		if len(changes) == 0 {
+			_, err := fmt.Fprintln(w, "_No changes._")
+			return err
+		}
+		fmt.Fprintln(w, "| Field | Change | Current | New |")
+		fmt.Fprintln(w, "|---|---|---|---|")
+		for _, c := range changes {
+			label := map[string]string{"add": "➕ added", "remove": "➖ removed", "change": "✏️ changed"}[c.Op]
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", c.Field(), label, cell(c.Old, c.Op != "add"), cell(c.New, c.Op != "remove"))
+		}
+		_, err := fmt.Fprintf(w, "\n%d field(s) changed.\n", len(changes))
+		return err
+	default:
+		paint := func(prefix, code, text string) string {
+			if !colored {
+				return prefix + text
+			}
+			return code + prefix + text + colorReset
+		}
+		fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+		if len(changes) == 0 {
+			_, err := fmt.Fprintln(w, "No changes detected.")
+			return err
+		}
+		for _, c := range changes {
+			if c.Op != "add" {
+				fmt.Fprintln(w, paint("- ", colorRed, c.Field()+": "+diffValue(c.Old)))
+			}
+			if c.Op != "remove" {
+				fmt.Fprintln(w, paint("+ ", colorGreen, c.Field()+": "+diffValue(c.New)))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package codesecurity
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionFormat names saved versions so that file names sort by time.
+const VersionFormat = "20060102-150405"
+
+// Version is one saved state of a configuration in a history directory.
+type Version struct {
+	Version string
+	Path    string
+	Saved   string
+	Changed string
+}
+
+// historyDir is <dir>/<org>/<config>; organization names are not case sensitive.
+func historyDir(dir, org, configName string) string {
+	return filepath.Join(dir, FileName(strings.ToLower(org)), FileName(configName))
+}
+
+// SaveHistory writes the remote state of a configuration before it is overwritten as
+// <dir>/<org>/<config>/<version>.yaml, in the same format the YAML files are read in, and returns the file path.
+// action names the change in the header, e.g. "update" or "copy".
+func SaveHistory(dir, org string, previous Config, changed []string, action string) (string, error) {
+	now := time.Now().UTC()
+	configDir := historyDir(dir, org, previous.Name)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", err
+	}
+	version := now.Format(VersionFormat)
+	path := filepath.Join(configDir, version+".yaml")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(configDir, fmt.Sprintf("%s-%d.yaml", version, i))
+	}
+	fields := Fields(previous)
+	if previous.DefaultForNewRepos != "" {
+		// Kept so that reverting to this version also restores the default for new repositories
+		fields["default_for_new_repos"] = previous.DefaultForNewRepos
+	}
+	body, err := yaml.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("# Configuration '%s' in organization '%s' before the %s at %s\n", previous.Name, org, action, now.Format(time.RFC3339))
+	header += fmt.Sprintf("# org: %s\n# saved: %s\n# changed: %s\n\n", org, now.Format(time.RFC3339), strings.Join(changed, ", "))
+	return path, ioutil.WriteFile(path, append([]byte(header), body...), 0644)
+}
+
+// ListHistory returns the saved versions of a configuration in an organization, oldest first.
+func ListHistory(dir, org, configName string) ([]Version, error) {
+	files, err := filepath.Glob(filepath.Join(historyDir(dir, org, configName), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var versions []Version
+	for _, file := range files {
+		v := Version{Version: strings.TrimSuffix(filepath.Base(file), ".yaml"), Path: file}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, "# ") {
+				break
+			}
+			if k, val, ok := strings.Cut(strings.TrimPrefix(line, "# "), ": "); ok {
+				switch k {
+				case "saved":
+					v.Saved = val
+				case "changed":
+					v.Changed = val
+				}
+			}
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		ti, ni := versionOrder(versions[i].Version)
+		tj, nj := versionOrder(versions[j].Version)
+		if ti != tj {
+			return ti < tj
+		}
+		return ni < nj
+	})
+	return versions, nil
+}
+
+// versionOrder splits a version into its timestamp and its counter: 1 for <ts>, n for the n-th save <ts>-n
+// within the same second. File names alone do not sort that way, "-" comes before ".".
+func versionOrder(version string) (string, int) {
+	if len(version) <= len(VersionFormat) {
+		return version, 1
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(version[len(VersionFormat):], "-"))
+	if err != nil {
+		return version, 1
+	}
+	return version[:len(VersionFormat)], n
+}
+
+// FindVersion resolves "latest", a full version or a unique prefix of one.
+func FindVersion(versions []Version, want string) (Version, error) {
+	if len(versions) == 0 {
+		return Version{}, fmt.Errorf("no saved versions")
+	}
+	if want == "latest" {
+		return versions[len(versions)-1], nil
+	}
+	var matches []Version
+	for _, v := range versions {
+		if v.Version == want {
+			return v, nil
+		}
+		if strings.HasPrefix(v.Version, want) {
+			matches = append(matches, v)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return Version{}, fmt.Errorf("version '%s' is ambiguous (%d matches)", want, len(matches))
+	}
+	return Version{}, fmt.Errorf("version '%s' not found", want)
+}
@@ -0,0 +1,163 @@
+package codesecurity
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyRule is one guardrail of a policy file. Field is a top-level field or a dotted path into the nested
+// options, e.g. code_scanning_default_setup_options.runner_type.
+type PolicyRule struct {
+	Field string `yaml:"field"`
+	// Forbid lists values the field may not be changed to
+	Forbid []string `yaml:"forbid"`
+	// Locked means the field may not change at all; with When, only while it has that value
+	Locked bool   `yaml:"locked"`
+	When   string `yaml:"when"`
+	// Order lists values from weakest to strongest; a change to a weaker value is a violation
+	Order  []string `yaml:"order"`
+	Reason string   `yaml:"reason"`
+}
+
+// Policy is the guardrails file checked against the diff before a configuration is changed.
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyPath resolves the guardrails file from the command flag, then UPDATE_POLICY, then the workspace default.
+// required is true when the flag names the file, which then must exist.
+func PolicyPath(flagValue string) (path string, required bool) {
+	if flagValue != "" {
+		return flagValue, true
+	}
+	if env := os.Getenv("UPDATE_POLICY"); env != "" {
+		return env, false
+	}
+	return "workspace/update_policy.yaml", false
+}
+
+// LoadPolicy reads a guardrails file. A file the user asked for must exist (required); default paths are only
+// used when they do, and nil is returned when they don't.
+func LoadPolicy(path string, required bool) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i, r := range p.Rules {
+		if r.Field == "" {
+			return nil, fmt.Errorf("%s: rule %d has no field", path, i+1)
+		}
+		if len(r.Forbid) == 0 && !r.Locked && len(r.Order) == 0 {
+			return nil, fmt.Errorf("%s: rule for %s needs forbid, locked or order", path, r.Field)
+		}
+	}
+	return &p, nil
+}
+
+// Check evaluates the rules against the changes of a Diff and describes each violation. A nil policy has no rules.
+func (p *Policy) Check(changes []Change) []string {
+	if p == nil {
+		return nil
+	}
+	var violations []string
+	for _, r := range p.Rules {
+		for _, c := range changes {
+			field, from, to, ok := valuesAt(c, r.Field)
+			if !ok || from == to {
+				continue
+			}
+			var broken string
+			switch {
+			case r.Locked && (r.When == "" || r.When == from):
+				broken = "may not change"
+				if r.When != "" {
+					broken = fmt.Sprintf("may not change once %s", r.When)
+				}
+			case indexOf(r.Forbid, to) >= 0:
+				broken = fmt.Sprintf("may not be set to %s", to)
+			case len(r.Order) > 0:
+				// Values outside the order (e.g. not set) are not ranked
+				oldRank, newRank := indexOf(r.Order, from), indexOf(r.Order, to)
+				if oldRank >= 0 && newRank >= 0 && newRank < oldRank {
+					broken = "may not be reduced"
+				}
+			}
+			if broken == "" {
+				continue
+			}
+			v := fmt.Sprintf("%s: %s -> %s (%s)", field, displayValue(from), displayValue(to), broken)
+			if r.Reason != "" {
+				v += ": " + r.Reason
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// valuesAt returns the value of field before and after c. When c is inside the field (e.g. one reviewer of a
+// locked list) the values and path of that part are returned; when c adds or removes an object containing the
+// field, the field is looked up in it. ok is false when c does not touch the field.
+func valuesAt(c Change, field string) (path, from, to string, ok bool) {
+	path = c.Field()
+	before, after := c.Old, c.New
+	switch {
+	case path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"["):
+	case strings.HasPrefix(field, path+"."):
+		for _, k := range strings.Split(strings.TrimPrefix(field, path+"."), ".") {
+			before, after = lookup(before, k), lookup(after, k)
+		}
+		path = field
+	default:
+		return "", "", "", false
+	}
+	if c.Op == "add" {
+		before = nil
+	}
+	if c.Op == "remove" {
+		after = nil
+	}
+	return path, policyValue(before), policyValue(after), true
+}
+
+func lookup(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// policyValue is the value rules compare against; a field that is not set is "".
+func policyValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return diffValue(v)
+}
+
+func indexOf(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func displayValue(s string) string {
+	if s == "" {
+		return "(not set)"
+	}
+	return s
+}
@@ -0,0 +1,193 @@
+// Package configyaml loads code security configuration YAML files with extends and ${VAR} substitution.
+package configyaml
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is put before a variable name to read it from the environment: ${ENV} reads CFG_ENV. Other
+// environment variables, tokens included, are never substituted into a configuration.
+const EnvPrefix = "CFG_"
+
+// varPattern matches $$ (a literal $), ${NAME} and ${NAME:-default} in configuration YAML values.
+var varPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Vars collects repeated -var NAME=value flags.
+type Vars map[string]string
+
+func (v Vars) String() string { return "" }
+
+func (v Vars) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected NAME=value, got '%s'", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// Load reads a configuration file, merges it over the files named in its extends key and substitutes
+// ${VAR} references, returning the effective YAML. vars take precedence over EnvPrefix variables of the
+// environment.
+func Load(path string, vars map[string]string) ([]byte, error) {
+	root, err := resolveExtends(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	missing := make(map[string]bool)
+	substituteVars(root, vars, missing)
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%s: undefined variable(s) %s, set them with -var NAME=value or %sNAME in the environment", path, strings.Join(names, ", "), EnvPrefix)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// resolveExtends loads a YAML mapping and, when it has an extends key (a path or a list of paths relative to the
+// file), merges its own keys over the merged base files.
+func resolveExtends(path string, chain []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range chain {
+		if seen == abs {
+			return nil, fmt.Errorf("extends cycle: %s", strings.Join(append(chain, abs), " -> "))
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of configuration fields", path)
+	}
+	var bases []string
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value != "extends" {
+			continue
+		}
+		switch value := root.Content[i+1]; value.Kind {
+		case yaml.ScalarNode:
+			bases = append(bases, value.Value)
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				bases = append(bases, item.Value)
+			}
+		default:
+			return nil, fmt.Errorf("%s: extends must be a file or a list of files", path)
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		break
+	}
+	if len(bases) == 0 {
+		return root, nil
+	}
+	var merged *yaml.Node
+	for _, base := range bases {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(path), base)
+		}
+		node, err := resolveExtends(base, append(chain, abs))
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = node
+		} else {
+			mergeNodes(merged, node)
+		}
+	}
+	mergeNodes(merged, root)
+	return merged, nil
+}
+
+// mergeNodes applies the keys of override to base. Nested mappings merge key by key; any other value, lists
+// included, replaces the base value.
+func mergeNodes(base, override *yaml.Node) {
+	for i := 0; i < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		found := false
+		for j := 0; j < len(base.Content); j += 2 {
+			if base.Content[j].Value != key.Value {
+				continue
+			}
+			if base.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeNodes(base.Content[j+1], value)
+			} else {
+				base.Content[j+1] = value
+			}
+			found = true
+			break
+		}
+		if !found {
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
+
+// substituteVars replaces ${VAR} references in the values below n and records the names that are not defined.
+func substituteVars(n *yaml.Node, vars map[string]string, missing map[string]bool) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			substituteVars(n.Content[i], vars, missing)
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			substituteVars(item, vars, missing)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return
+		}
+		n.Value = varPattern.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if ref == "$$" {
+				return "$"
+			}
+			m := varPattern.FindStringSubmatch(ref)
+			if v, ok := vars[m[1]]; ok {
+				return v
+			}
+			if v, ok := os.LookupEnv(EnvPrefix + m[1]); ok {
+				return v
+			}
+			if m[2] != "" {
+				return m[3]
+			}
+			missing[m[1]] = true
+			return ref
+		})
+		// Unquoted values are typed after substitution, so ${LABELED:-false} is a bool and ${LABEL:-null} is null
+		if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.TaggedStyle) == 0 {
+			n.Tag = ""
+		}
+	}
+}
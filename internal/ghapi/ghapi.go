@@ -0,0 +1,141 @@
+// Package ghapi holds the GitHub REST helpers shared by the commands.
+package ghapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// NewRequest builds a GitHub REST request with the headers every command sends.
+func NewRequest(method, url, token string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// ListOrgRepoNames returns the names of every repository of the organization.
+func ListOrgRepoNames(client *http.Client, apiBase, org, token string) ([]string, error) {
+	var names []string
+	page := 1
+	perPage := 100
+	for {
+		url := fmt.Sprintf("%s/orgs/%s/repos?type=all&per_page=%d&page=%d", apiBase, org, perPage, page)
+		req, err := NewRequest("GET", url, token, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("API error getting repos: %s\n%s", resp.Status, string(body))
+		}
+		var repos []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&repos)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repos JSON: %w", err)
+		}
+		for _, r := range repos {
+			names = append(names, r.Name)
+		}
+		if len(repos) < perPage && !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+			break
+		}
+		page++
+	}
+	return names, nil
+}
+
+// NextPageURL returns the rel="next" URL of a Link header, or "" on the last page.
+func NextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		sections := strings.Split(part, ";")
+		if len(sections) < 2 {
+			continue
+		}
+		if strings.TrimSpace(sections[1]) == `rel="next"` {
+			return strings.Trim(strings.TrimSpace(sections[0]), "<>")
+		}
+	}
+	return ""
+}
+
+// GetAll follows the Link header of a GET and decodes every page into out, a pointer to a slice.
+func GetAll(client *http.Client, url, token string, out interface{}) error {
+	var items []json.RawMessage
+	for next := url; next != ""; {
+		req, err := NewRequest("GET", next, token, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+		}
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("failed to parse JSON (expected array): %w", err)
+		}
+		items = append(items, batch...)
+		next = NextPageURL(resp.Header.Get("Link"))
+	}
+	all, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(all, out)
+}
+
+// Send makes a write request with payload as the JSON body and returns the response body, or an error with
+// GitHub's message. A nil payload sends no body.
+func Send(client *http.Client, method, url, token string, payload interface{}) (int, []byte, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+	}
+	req, err := NewRequest(method, url, token, data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, respBody, fmt.Errorf("API error: %s\n%s", resp.Status, string(respBody))
+	}
+	return resp.StatusCode, respBody, nil
+}
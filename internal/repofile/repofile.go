@@ -0,0 +1,103 @@
+// Package repofile reads the repository lists the commands accept with -repo-file: get_org_repos.go output,
+// inventory files, YAML/JSON lists and plain text.
+package repofile
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NamesFromValue extracts repository names from a decoded YAML/JSON document.
+// It accepts a plain list, a list of objects with name/full_name, or a mapping
+// holding such a list under "repositories" (get_org_repos output) or "inventory".
+func NamesFromValue(v interface{}) ([]string, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"repositories", "inventory", "repos"} {
+			if list, ok := t[key]; ok {
+				return NamesFromValue(list)
+			}
+		}
+		return nil, false
+	case []interface{}:
+		var names []string
+		for _, item := range t {
+			switch it := item.(type) {
+			case string:
+				names = append(names, it)
+			case map[string]interface{}:
+				if full, ok := it["full_name"].(string); ok && full != "" {
+					names = append(names, full)
+				} else if name, ok := it["name"].(string); ok && name != "" {
+					names = append(names, name)
+				}
+			default:
+				if it != nil {
+					names = append(names, fmt.Sprint(it))
+				}
+			}
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// ParseRepoList reads repository names from a YAML/JSON list, an
+// inventory file or plain text (one per line or comma/semicolon separated).
+// Names may be qualified as org/name; entries for another org are skipped.
+func ParseRepoList(path, org string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read repo file '%s': %w", path, err)
+	}
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return "", fmt.Errorf("repo file '%s' is empty", path)
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+
+	var parts []string
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err == nil {
+		if names, ok := NamesFromValue(doc); ok {
+			parts = names
+		}
+	} else if strings.HasPrefix(content, "[") || strings.HasPrefix(content, "{") || strings.Contains(content, "repositories:") {
+		return "", fmt.Errorf("failed to parse repo file '%s': %w", path, err)
+	}
+	if parts == nil {
+		seps := func(r rune) bool { return r == '\n' || r == ',' || r == ';' }
+		parts = strings.FieldsFunc(content, seps)
+	}
+
+	uniq := make([]string, 0, len(parts))
+	seen := make(map[string]struct{})
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		if i := strings.Index(p, "/"); i >= 0 {
+			owner := p[:i]
+			if org != "" && !strings.EqualFold(owner, org) {
+				log.Printf("Skipping '%s' from repo file: belongs to organization '%s', not '%s'", p, owner, org)
+				continue
+			}
+			p = p[i+1:]
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		uniq = append(uniq, p)
+	}
+	if len(uniq) == 0 {
+		return "", fmt.Errorf("repo file '%s' contains no valid repositories", path)
+	}
+	return strings.Join(uniq, ","), nil
+}
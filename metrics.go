@@ -0,0 +1,531 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+// Feature keys reported in the snapshot, in display order.
+var featureKeys = []string{
+	"advanced_security",
+	"secret_scanning",
+	"secret_scanning_push_protection",
+	"dependabot_security_updates",
+	"code_scanning_default_setup",
+}
+
+type orgRepo struct {
+	Name                string `json:"name"`
+	Archived            bool   `json:"archived"`
+	SecurityAndAnalysis map[string]struct {
+		Status string `json:"status"`
+	} `json:"security_and_analysis"`
+}
+
+// RepoMetrics is the per-repository part of the snapshot, used to spot regressions between snapshots.
+type RepoMetrics struct {
+	Name                string          `json:"name"`
+	Features            map[string]bool `json:"features"`
+	OpenAlerts          map[string]int  `json:"open_alerts"`
+	Configuration       string          `json:"configuration,omitempty"`
+	ConfigurationStatus string          `json:"configuration_status,omitempty"`
+}
+
+type FeatureMetric struct {
+	Enabled int     `json:"enabled"`
+	Percent float64 `json:"percent"`
+}
+
+type ConfigAttachment struct {
+	ID       int            `json:"id"`
+	Name     string         `json:"name"`
+	Attached int            `json:"attached"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+type AlertCounts struct {
+	Total      int            `json:"total"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// MTTRMetric is the mean time to remediate alerts fixed inside the MTTR window.
+type MTTRMetric struct {
+	Remediated int     `json:"remediated"`
+	MeanHours  float64 `json:"mean_hours"`
+	MeanDays   float64 `json:"mean_days"`
+}
+
+// MetricsSnapshot is the JSON document written by this command.
+type MetricsSnapshot struct {
+	Organization      string                   `json:"organization"`
+	GeneratedAt       time.Time                `json:"generated_at"`
+	TotalRepos        int                      `json:"total_repos"`
+	Features          map[string]FeatureMetric `json:"features"`
+	ConfigAttachments []ConfigAttachment       `json:"config_attachments"`
+	OpenAlerts        map[string]AlertCounts   `json:"open_alerts"`
+	MTTR              map[string]MTTRMetric    `json:"mttr"`
+	MTTRWindowDays    int                      `json:"mttr_window_days"`
+	Repositories      []RepoMetrics            `json:"repositories"`
+}
+
+// parseWindow accepts a Go duration or a number of days ("90d").
+func parseWindow(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid window '%s'", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// getAllPages follows Link headers and decodes each page with decode.
+// It returns the status code of a failed request so callers can treat 403/404 specially.
+func getAllPages(client *http.Client, firstURL, token string, decode func([]byte) error) (int, error) {
+	next := firstURL
+	for next != "" {
+		req, err := ghapi.NewRequest("GET", next, token, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("request failed: %w", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return resp.StatusCode, fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+		}
+		if err := decode(body); err != nil {
+			return resp.StatusCode, err
+		}
+		next = ghapi.NextPageURL(resp.Header.Get("Link"))
+	}
+	return http.StatusOK, nil
+}
+
+// defaultSetupConfigured reports whether code scanning default setup is configured on a repository.
+func defaultSetupConfigured(client *http.Client, apiBase, org, repo, token string) (bool, error) {
+	req, err := ghapi.NewRequest("GET", fmt.Sprintf("%s/repos/%s/%s/code-scanning/default-setup", apiBase, org, repo), token, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		// Code scanning is not available for this repository
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+	}
+	var setup struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &setup); err != nil {
+		return false, err
+	}
+	return setup.State == "configured", nil
+}
+
+// alertRecord is the common shape used to count one alert of any type.
+type alertRecord struct {
+	Repo       string
+	Open       bool
+	Severity   string
+	Remediated bool
+	CreatedAt  time.Time
+	FixedAt    time.Time
+}
+
+// fetchAlerts lists every alert of one type and maps it to alertRecord.
+// Code scanning and Dependabot count "fixed" as remediated, secret scanning counts "revoked".
+func fetchAlerts(client *http.Client, apiBase, org, token, alertType string) ([]alertRecord, int, error) {
+	var records []alertRecord
+	query := url.Values{}
+	query.Set("per_page", "100")
+	var path string
+	var decode func([]byte) error
+	switch alertType {
+	case "code_scanning":
+		path = "code-scanning/alerts"
+		decode = func(body []byte) error {
+			var batch []struct {
+				State string `json:"state"`
+				Rule  struct {
+					Severity              string `json:"severity"`
+					SecuritySeverityLevel string `json:"security_severity_level"`
+				} `json:"rule"`
+				CreatedAt  time.Time  `json:"created_at"`
+				FixedAt    *time.Time `json:"fixed_at"`
+				Repository struct {
+					Name string `json:"name"`
+				} `json:"repository"`
+			}
+			if err := json.Unmarshal(body, &batch); err != nil {
+				return fmt.Errorf("failed to parse code scanning alerts JSON: %w", err)
+			}
+			for _, a := range batch {
+				r := alertRecord{Repo: a.Repository.Name, Open: a.State == "open", Severity: a.Rule.SecuritySeverityLevel, CreatedAt: a.CreatedAt}
+				if r.Severity == "" {
+					r.Severity = a.Rule.Severity
+				}
+				if a.State == "fixed" && a.FixedAt != nil {
+					r.Remediated, r.FixedAt = true, *a.FixedAt
+				}
+				records = append(records, r)
+			}
+			return nil
+		}
+	case "dependabot":
+		path = "dependabot/alerts"
+		decode = func(body []byte) error {
+			var batch []struct {
+				State            string `json:"state"`
+				SecurityAdvisory struct {
+					Severity string `json:"severity"`
+				} `json:"security_advisory"`
+				CreatedAt  time.Time  `json:"created_at"`
+				FixedAt    *time.Time `json:"fixed_at"`
+				Repository struct {
+					Name string `json:"name"`
+				} `json:"repository"`
+			}
+			if err := json.Unmarshal(body, &batch); err != nil {
+				return fmt.Errorf("failed to parse Dependabot alerts JSON: %w", err)
+			}
+			for _, a := range batch {
+				r := alertRecord{Repo: a.Repository.Name, Open: a.State == "open", Severity: a.SecurityAdvisory.Severity, CreatedAt: a.CreatedAt}
+				if a.State == "fixed" && a.FixedAt != nil {
+					r.Remediated, r.FixedAt = true, *a.FixedAt
+				}
+				records = append(records, r)
+			}
+			return nil
+		}
+	case "secret_scanning":
+		path = "secret-scanning/alerts"
+		query.Set("hide_secret", "true")
+		decode = func(body []byte) error {
+			var batch []struct {
+				State      string     `json:"state"`
+				Resolution string     `json:"resolution"`
+				Validity   string     `json:"validity"`
+				CreatedAt  time.Time  `json:"created_at"`
+				ResolvedAt *time.Time `json:"resolved_at"`
+				Repository struct {
+					Name string `json:"name"`
+				} `json:"repository"`
+			}
+			if err := json.Unmarshal(body, &batch); err != nil {
+				return fmt.Errorf("failed to parse secret scanning alerts JSON: %w", err)
+			}
+			for _, a := range batch {
+				// Secret scanning has no severity, validity is the closest signal
+				r := alertRecord{Repo: a.Repository.Name, Open: a.State == "open", Severity: a.Validity, CreatedAt: a.CreatedAt}
+				if a.Resolution == "revoked" && a.ResolvedAt != nil {
+					r.Remediated, r.FixedAt = true, *a.ResolvedAt
+				}
+				records = append(records, r)
+			}
+			return nil
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown alert type '%s'", alertType)
+	}
+	status, err := getAllPages(client, fmt.Sprintf("%s/orgs/%s/%s?%s", apiBase, org, path, query.Encode()), token, decode)
+	return records, status, err
+}
+
+// appendToStore adds the snapshot as one line of the JSONL history store read by trend.go.
+func appendToStore(path string, snapshot MetricsSnapshot) error {
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	output := flag.String("output", "", "Snapshot file (default workspace/<org>-metrics-<date>.json)")
+	mttrWindow := flag.String("mttr-window", "90d", "Only alerts remediated within this window count towards MTTR (e.g. 30d, 90d)")
+	includeArchived := flag.Bool("include-archived", false, "Include archived repositories in feature percentages")
+	defaultSetup := flag.Bool("default-setup", true, "Check code scanning default setup per repository (one request per repository)")
+	storePath := flag.String("store", "workspace/metrics-history.jsonl", "Append the snapshot to this history store for trend reports (empty to disable)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" {
+		log.Fatal("Usage: go run metrics.go -org <org> [-output file] [-mttr-window 90d] [-include-archived] [-default-setup=false]")
+	}
+	window, err := parseWindow(*mttrWindow)
+	if err != nil {
+		log.Fatalf("Invalid -mttr-window: %v", err)
+	}
+	now := time.Now().UTC()
+	if *output == "" {
+		*output = fmt.Sprintf("workspace/%s-metrics-%s.json", *org, now.Format("2006-01-02"))
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	snapshot := MetricsSnapshot{
+		Organization:   *org,
+		GeneratedAt:    now,
+		Features:       make(map[string]FeatureMetric),
+		OpenAlerts:     make(map[string]AlertCounts),
+		MTTR:           make(map[string]MTTRMetric),
+		MTTRWindowDays: int(window.Hours() / 24),
+	}
+
+	// 1. Repositories and feature enablement
+	fmt.Printf("Collecting metrics for '%s'...\n", *org)
+	var repos []orgRepo
+	_, err = getAllPages(client, fmt.Sprintf("%s/orgs/%s/repos?type=all&per_page=100&page=1", apiBase, *org), githubToken, func(body []byte) error {
+		var batch []orgRepo
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("failed to parse repos JSON: %w", err)
+		}
+		repos = append(repos, batch...)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to list repositories: %v", err)
+	}
+
+	repoIndex := make(map[string]*RepoMetrics)
+	missingSettings := 0
+	for _, r := range repos {
+		if r.Archived && !*includeArchived {
+			continue
+		}
+		rm := RepoMetrics{Name: r.Name, Features: make(map[string]bool), OpenAlerts: make(map[string]int)}
+		if r.SecurityAndAnalysis == nil {
+			missingSettings++
+		}
+		for _, key := range featureKeys {
+			if key == "code_scanning_default_setup" {
+				continue
+			}
+			rm.Features[key] = r.SecurityAndAnalysis[key].Status == "enabled"
+		}
+		if *defaultSetup {
+			configured, err := defaultSetupConfigured(client, apiBase, *org, r.Name, githubToken)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not read default setup for %s: %v\n", r.Name, err)
+			}
+			rm.Features["code_scanning_default_setup"] = configured
+		}
+		snapshot.Repositories = append(snapshot.Repositories, rm)
+	}
+	sort.Slice(snapshot.Repositories, func(i, j int) bool { return snapshot.Repositories[i].Name < snapshot.Repositories[j].Name })
+	for i := range snapshot.Repositories {
+		repoIndex[snapshot.Repositories[i].Name] = &snapshot.Repositories[i]
+	}
+	snapshot.TotalRepos = len(snapshot.Repositories)
+	if missingSettings > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d repositories returned no security_and_analysis settings (token needs admin access); they count as disabled\n", missingSettings)
+	}
+	for _, key := range featureKeys {
+		if key == "code_scanning_default_setup" && !*defaultSetup {
+			continue
+		}
+		m := FeatureMetric{}
+		for _, rm := range snapshot.Repositories {
+			if rm.Features[key] {
+				m.Enabled++
+			}
+		}
+		if snapshot.TotalRepos > 0 {
+			m.Percent = round1(float64(m.Enabled) * 100 / float64(snapshot.TotalRepos))
+		}
+		snapshot.Features[key] = m
+	}
+
+	// 2. Configuration attachments
+	type configSummary struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	var configs []configSummary
+	_, err = getAllPages(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", apiBase, *org), githubToken, func(body []byte) error {
+		var batch []configSummary
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("failed to parse configurations JSON: %w", err)
+		}
+		configs = append(configs, batch...)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to list configurations: %v", err)
+	}
+	for _, c := range configs {
+		ca := ConfigAttachment{ID: c.ID, Name: c.Name, ByStatus: make(map[string]int)}
+		_, err := getAllPages(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/repositories?per_page=100", apiBase, *org, c.ID), githubToken, func(body []byte) error {
+			var batch []struct {
+				Status     string `json:"status"`
+				Repository struct {
+					Name string `json:"name"`
+				} `json:"repository"`
+			}
+			if err := json.Unmarshal(body, &batch); err != nil {
+				return fmt.Errorf("failed to parse attachments JSON: %w", err)
+			}
+			for _, a := range batch {
+				ca.ByStatus[a.Status]++
+				if rm, ok := repoIndex[a.Repository.Name]; ok {
+					rm.Configuration, rm.ConfigurationStatus = c.Name, a.Status
+				}
+				if a.Status == "attached" {
+					ca.Attached++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not list repositories for configuration '%s': %v\n", c.Name, err)
+		}
+		snapshot.ConfigAttachments = append(snapshot.ConfigAttachments, ca)
+	}
+
+	// 3. Alerts and mean time to remediate
+	cutoff := now.Add(-window)
+	for _, alertType := range []string{"code_scanning", "secret_scanning", "dependabot"} {
+		records, status, err := fetchAlerts(client, apiBase, *org, githubToken, alertType)
+		if err != nil {
+			if status == http.StatusNotFound || status == http.StatusForbidden {
+				fmt.Fprintf(os.Stderr, "⚠️  %s alerts not available (%d), skipping\n", alertType, status)
+				continue
+			}
+			log.Fatalf("Failed to list %s alerts: %v", alertType, err)
+		}
+		counts := AlertCounts{BySeverity: make(map[string]int)}
+		var totalHours float64
+		remediated := 0
+		for _, r := range records {
+			if r.Open {
+				sev := r.Severity
+				if sev == "" {
+					sev = "unknown"
+				}
+				counts.Total++
+				counts.BySeverity[sev]++
+				if rm, ok := repoIndex[r.Repo]; ok {
+					rm.OpenAlerts[alertType]++
+				}
+			}
+			if r.Remediated && r.FixedAt.After(cutoff) {
+				remediated++
+				totalHours += r.FixedAt.Sub(r.CreatedAt).Hours()
+			}
+		}
+		snapshot.OpenAlerts[alertType] = counts
+		mttr := MTTRMetric{Remediated: remediated}
+		if remediated > 0 {
+			mttr.MeanHours = round1(totalHours / float64(remediated))
+			mttr.MeanDays = round1(mttr.MeanHours / 24)
+		}
+		snapshot.MTTR[alertType] = mttr
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal snapshot JSON: %v", err)
+	}
+	if err := ioutil.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+
+	if *storePath != "" {
+		if err := appendToStore(*storePath, snapshot); err != nil {
+			log.Fatalf("Failed to append snapshot to %s: %v", *storePath, err)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\nFeature adoption (%d repositories)\n", snapshot.TotalRepos)
+	for _, key := range featureKeys {
+		if m, ok := snapshot.Features[key]; ok {
+			fmt.Fprintf(w, "  %s\t%d\t%.1f%%\n", key, m.Enabled, m.Percent)
+		}
+	}
+	fmt.Fprintln(w, "\nConfiguration attachments")
+	for _, ca := range snapshot.ConfigAttachments {
+		fmt.Fprintf(w, "  %s\t%d\n", ca.Name, ca.Attached)
+	}
+	fmt.Fprintln(w, "\nOpen alerts / MTTR")
+	for _, alertType := range []string{"code_scanning", "secret_scanning", "dependabot"} {
+		counts, ok := snapshot.OpenAlerts[alertType]
+		if !ok {
+			continue
+		}
+		mttr := snapshot.MTTR[alertType]
+		fmt.Fprintf(w, "  %s\t%d open\tMTTR %.1f days (%d remediated)\n", alertType, counts.Total, mttr.MeanDays, mttr.Remediated)
+	}
+	w.Flush()
+	fmt.Printf("\n✅ Wrote metrics snapshot to %s\n", *output)
+	if *storePath != "" {
+		fmt.Printf("✅ Appended snapshot to %s\n", *storePath)
+	}
+}
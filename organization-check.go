@@ -175,18 +175,28 @@ func main() {
 					fmt.Printf("         Dependabot Security Updates: %s %s\n", status, icon)
 				}
 
-				// Try to check for Code Scanning by attempting to list alerts
-				_, resp, err := client.CodeScanning.ListAlertsForRepo(ctx, *org.Login, *repo.Name, &github.AlertListOptions{
-					ListOptions: github.ListOptions{PerPage: 1},
-				})
+				// Code Scanning default setup state (alerts are not a reliable signal: a repo
+				// with default setup enabled may simply have no alerts yet)
+				defaultSetup, resp, err := client.CodeScanning.GetDefaultSetupConfiguration(ctx, *org.Login, *repo.Name)
 				if err != nil {
 					if resp != nil && resp.StatusCode == 404 {
-						fmt.Printf("         Code Scanning (CodeQL): not configured ❌\n")
+						fmt.Printf("         Code Scanning (CodeQL): default setup not available ❌\n")
 					} else {
 						fmt.Printf("         Code Scanning (CodeQL): access denied or error ⚠️\n")
 					}
+				} else if defaultSetup.GetState() == "configured" {
+					fmt.Printf("         Code Scanning (CodeQL): default setup configured ✅ (languages: %s, query suite: %s)\n",
+						strings.Join(defaultSetup.Languages, ", "), defaultSetup.GetQuerySuite())
 				} else {
-					fmt.Printf("         Code Scanning (CodeQL): configured ✅\n")
+					// Not using default setup: check for analyses uploaded by an advanced setup workflow
+					analyses, _, err := client.CodeScanning.ListAnalysesForRepo(ctx, *org.Login, *repo.Name, &github.AnalysesListOptions{
+						ListOptions: github.ListOptions{PerPage: 1},
+					})
+					if err == nil && len(analyses) > 0 {
+						fmt.Printf("         Code Scanning (CodeQL): advanced setup (analyses found) ✅\n")
+					} else {
+						fmt.Printf("         Code Scanning (CodeQL): default setup %s ❌\n", defaultSetup.GetState())
+					}
 				}
 
 				// Try to check for Dependabot by attempting to list alerts
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github-secret-scanning/internal/ghapi"
+)
+
+type bypassedAlert struct {
+	Number                   int       `json:"number"`
+	State                    string    `json:"state"`
+	SecretType               string    `json:"secret_type"`
+	HTMLURL                  string    `json:"html_url"`
+	PushProtectionBypassed   bool      `json:"push_protection_bypassed"`
+	PushProtectionBypassedAt time.Time `json:"push_protection_bypassed_at"`
+	PushProtectionBypassedBy *struct {
+		Login string `json:"login"`
+	} `json:"push_protection_bypassed_by"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+// bypassRequest is a delegated bypass request (secret_scanning_delegated_bypass).
+type bypassRequest struct {
+	Number     int    `json:"number"`
+	Status     string `json:"status"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+	Requester struct {
+		ActorName string `json:"actor_name"`
+	} `json:"requester"`
+	Data []struct {
+		SecretType   string `json:"secret_type"`
+		BypassReason string `json:"bypass_reason"`
+	} `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+	HTMLURL   string    `json:"html_url"`
+}
+
+// BypassEvent is one row of the report, from either an alert or a bypass request.
+type BypassEvent struct {
+	Source     string
+	Repository string
+	User       string
+	SecretType string
+	Status     string
+	Reason     string
+	Time       time.Time
+	URL        string
+}
+
+// parseWindow accepts a Go duration or a number of days ("30d").
+func parseWindow(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid window '%s'", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// getAllPages follows Link headers and decodes each page with decode.
+// It returns the status code of a failed request so callers can treat 404 specially.
+func getAllPages(client *http.Client, firstURL, token string, decode func([]byte) error) (int, error) {
+	next := firstURL
+	for next != "" {
+		req, err := ghapi.NewRequest("GET", next, token, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("request failed: %w", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return resp.StatusCode, fmt.Errorf("API error: %s\n%s", resp.Status, string(body))
+		}
+		if err := decode(body); err != nil {
+			return resp.StatusCode, err
+		}
+		next = ghapi.NextPageURL(resp.Header.Get("Link"))
+	}
+	return http.StatusOK, nil
+}
+
+// timePeriodFor picks the smallest bypass-requests time_period covering the window. The API accepts hour, day,
+// week and month, so longer windows get the last month of requests; events are filtered by -since locally.
+func timePeriodFor(window time.Duration) string {
+	switch {
+	case window <= time.Hour:
+		return "hour"
+	case window <= 24*time.Hour:
+		return "day"
+	case window <= 7*24*time.Hour:
+		return "week"
+	default:
+		return "month"
+	}
+}
+
+func printGroup(title string, events []BypassEvent, key func(BypassEvent) string) {
+	type counts struct{ alerts, requests, approved, denied, pending int }
+	groups := make(map[string]*counts)
+	for _, e := range events {
+		k := key(e)
+		if k == "" {
+			k = "(unknown)"
+		}
+		c, ok := groups[k]
+		if !ok {
+			c = &counts{}
+			groups[k] = c
+		}
+		if e.Source == "alert" {
+			c.alerts++
+			continue
+		}
+		c.requests++
+		switch e.Status {
+		case "approved", "completed":
+			c.approved++
+		case "denied", "rejected", "cancelled", "expired":
+			c.denied++
+		default:
+			c.pending++
+		}
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ti := groups[keys[i]].alerts + groups[keys[i]].requests
+		tj := groups[keys[j]].alerts + groups[keys[j]].requests
+		if ti != tj {
+			return ti > tj
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Printf("\n%s\n", title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tBYPASSED ALERTS\tREQUESTS\tAPPROVED\tDENIED/EXPIRED\tPENDING")
+	for _, k := range keys {
+		c := groups[k]
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%d\t%d\n", k, c.alerts, c.requests, c.approved, c.denied, c.pending)
+	}
+	w.Flush()
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	since := flag.String("since", "30d", "Time window to report on (e.g. 7d, 30d, 720h)")
+	csvPath := flag.String("csv", "", "Also write every bypass event to this CSV file")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" {
+		log.Fatal("Usage: go run push_protection_bypass_report.go -org <org> [-since 30d] [-csv report.csv]")
+	}
+	window, err := parseWindow(*since)
+	if err != nil {
+		log.Fatalf("Invalid -since: %v", err)
+	}
+	cutoff := time.Now().Add(-window)
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	var events []BypassEvent
+
+	// 1. Alerts created by pushing through push protection
+	alertQuery := url.Values{}
+	alertQuery.Set("per_page", "100")
+	alertQuery.Set("hide_secret", "true")
+	alertsURL := fmt.Sprintf("%s/orgs/%s/secret-scanning/alerts?%s", apiBase, *org, alertQuery.Encode())
+	_, err = getAllPages(client, alertsURL, githubToken, func(body []byte) error {
+		var batch []bypassedAlert
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("failed to parse alerts JSON: %w", err)
+		}
+		for _, a := range batch {
+			if !a.PushProtectionBypassed || a.PushProtectionBypassedAt.Before(cutoff) {
+				continue
+			}
+			e := BypassEvent{
+				Source:     "alert",
+				Repository: a.Repository.Name,
+				SecretType: a.SecretType,
+				Status:     a.State,
+				Time:       a.PushProtectionBypassedAt,
+				URL:        a.HTMLURL,
+			}
+			if a.PushProtectionBypassedBy != nil {
+				e.User = a.PushProtectionBypassedBy.Login
+			}
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to list secret scanning alerts: %v", err)
+	}
+
+	// 2. Delegated bypass requests
+	requestQuery := url.Values{}
+	requestQuery.Set("per_page", "100")
+	requestQuery.Set("time_period", timePeriodFor(window))
+	if window > 30*24*time.Hour {
+		fmt.Fprintln(os.Stderr, "⚠️  The bypass requests API only goes back one month, older requests are not reported")
+	}
+	requestsURL := fmt.Sprintf("%s/orgs/%s/bypass-requests/secret-scanning?%s", apiBase, *org, requestQuery.Encode())
+	status, err := getAllPages(client, requestsURL, githubToken, func(body []byte) error {
+		var batch []bypassRequest
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("failed to parse bypass requests JSON: %w", err)
+		}
+		for _, r := range batch {
+			if r.CreatedAt.Before(cutoff) {
+				continue
+			}
+			e := BypassEvent{
+				Source:     "request",
+				Repository: r.Repository.Name,
+				User:       r.Requester.ActorName,
+				Status:     r.Status,
+				Time:       r.CreatedAt,
+				URL:        r.HTMLURL,
+			}
+			var types, reasons []string
+			for _, d := range r.Data {
+				types = append(types, d.SecretType)
+				if d.BypassReason != "" {
+					reasons = append(reasons, d.BypassReason)
+				}
+			}
+			e.SecretType = strings.Join(types, ",")
+			e.Reason = strings.Join(reasons, ",")
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		if status == http.StatusNotFound {
+			fmt.Fprintln(os.Stderr, "⚠️  Bypass requests endpoint not available on this server, reporting bypassed alerts only")
+		} else {
+			log.Fatalf("Failed to list bypass requests: %v", err)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+
+	alertCount := 0
+	for _, e := range events {
+		if e.Source == "alert" {
+			alertCount++
+		}
+	}
+	fmt.Printf("Push protection bypasses in '%s' since %s\n", *org, cutoff.Format("2006-01-02"))
+	fmt.Printf("  Bypassed alerts: %d\n  Bypass requests: %d\n", alertCount, len(events)-alertCount)
+	if len(events) > 0 {
+		printGroup("By repository", events, func(e BypassEvent) string { return e.Repository })
+		printGroup("By user", events, func(e BypassEvent) string { return e.User })
+		printGroup("By secret type", events, func(e BypassEvent) string { return e.SecretType })
+	}
+
+	// Written even without events, so a scheduled export always leaves a file with the header
+	if *csvPath != "" {
+		f, err := os.Create(*csvPath)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *csvPath, err)
+		}
+		defer f.Close()
+		w := csv.NewWriter(f)
+		w.Write([]string{"source", "repository", "user", "secret_type", "status", "reason", "time", "url"})
+		for _, e := range events {
+			w.Write([]string{e.Source, e.Repository, e.User, e.SecretType, e.Status, e.Reason, e.Time.UTC().Format(time.RFC3339), e.URL})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+		fmt.Printf("\nWrote %d bypass events to %s\n", len(events), *csvPath)
+	}
+}
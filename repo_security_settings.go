@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/ghapi"
+	"github-secret-scanning/internal/repofile"
+)
+
+// securitySetting maps a command line flag to its security_and_analysis key.
+type securitySetting struct {
+	Flag  string
+	Key   string
+	Label string
+}
+
+var securitySettings = []securitySetting{
+	{"advanced-security", "advanced_security", "Advanced Security"},
+	{"secret-scanning", "secret_scanning", "Secret Scanning"},
+	{"push-protection", "secret_scanning_push_protection", "Push Protection"},
+	{"non-provider-patterns", "secret_scanning_non_provider_patterns", "Non-provider Patterns"},
+	{"validity-checks", "secret_scanning_validity_checks", "Validity Checks"},
+	{"dependabot-security-updates", "dependabot_security_updates", "Dependabot Security Updates"},
+}
+
+type securityAndAnalysis map[string]struct {
+	Status string `json:"status"`
+}
+
+type repoSecurity struct {
+	Name                string              `json:"name"`
+	Archived            bool                `json:"archived"`
+	SecurityAndAnalysis securityAndAnalysis `json:"security_and_analysis"`
+}
+
+// doRepoRequest sends a GET or PATCH to /repos/{org}/{repo} and decodes the repository.
+func doRepoRequest(client *http.Client, method, apiBase, org, repo, token string, body []byte) (*repoSecurity, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", apiBase, org, repo)
+	req, err := ghapi.NewRequest(method, url, token, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API error: %s\n%s", resp.Status, string(respBody))
+	}
+	var info repoSecurity
+	if err := json.Unmarshal(respBody, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse repo JSON: %w", err)
+	}
+	return &info, nil
+}
+
+func statusOf(sa securityAndAnalysis, key string) string {
+	if v, ok := sa[key]; ok && v.Status != "" {
+		return v.Status
+	}
+	return "n/a"
+}
+
+func main() {
+	repo := flag.String("repo", "", "Repository name, comma-separated list, 'all' or path to a repo list file")
+	repoFile := flag.String("repo-file", "", "Path to a repo list file (same formats as add_repo_to_config -repo-file)")
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	dryRun := flag.Bool("dry-run", false, "Only show the current settings and the planned changes")
+	yes := flag.Bool("yes", false, "Apply without asking for confirmation")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	wanted := make(map[string]*string)
+	for _, s := range securitySettings {
+		wanted[s.Key] = flag.String(s.Flag, "", fmt.Sprintf("Set %s: enabled or disabled (unchanged if empty)", s.Label))
+	}
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+
+	if *repo != "" {
+		if info, err := os.Stat(*repo); err == nil && !info.IsDir() {
+			parsed, perr := repofile.ParseRepoList(*repo, *org)
+			if perr != nil {
+				log.Fatalf("Error parsing repo file: %v", perr)
+			}
+			*repo = parsed
+		}
+	}
+	if *repo == "" && *repoFile != "" {
+		parsed, perr := repofile.ParseRepoList(*repoFile, *org)
+		if perr != nil {
+			log.Fatalf("Error parsing repo file: %v", perr)
+		}
+		*repo = parsed
+	}
+	if *repo == "" || *org == "" {
+		log.Fatal("Usage: go run repo_security_settings.go -org <org> -repo <repo|list|all|file> [-push-protection enabled] [-secret-scanning enabled] ... [-dry-run] [-yes]")
+	}
+
+	changes := make(map[string]interface{})
+	var changedKeys []string
+	for _, s := range securitySettings {
+		v := strings.TrimSpace(*wanted[s.Key])
+		if v == "" {
+			continue
+		}
+		if v != "enabled" && v != "disabled" {
+			log.Fatalf("Invalid value '%s' for -%s: must be enabled or disabled", v, s.Flag)
+		}
+		changes[s.Key] = map[string]string{"status": v}
+		changedKeys = append(changedKeys, s.Key)
+	}
+	if len(changes) == 0 {
+		log.Fatal("No setting selected. Use at least one of -advanced-security, -secret-scanning, -push-protection, -non-provider-patterns, -validity-checks, -dependabot-security-updates")
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	var repoNames []string
+	if *repo == "all" {
+		names, err := ghapi.ListOrgRepoNames(client, apiBase, *org, githubToken)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		repoNames = names
+	} else {
+		for _, name := range strings.Split(*repo, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				repoNames = append(repoNames, name)
+			}
+		}
+	}
+	if len(repoNames) == 0 {
+		log.Fatalf("No repositories selected in organization '%s'", *org)
+	}
+
+	// Read current settings and work out which repositories actually change
+	before := make(map[string]*repoSecurity)
+	var targets []string
+	fmt.Println("--- Planned changes ---")
+	for _, name := range repoNames {
+		info, err := doRepoRequest(client, "GET", apiBase, *org, name, githubToken, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  %s: failed to read settings: %v\n", name, err)
+			continue
+		}
+		if info.Archived {
+			fmt.Printf("  %s: archived, skipped\n", name)
+			continue
+		}
+		before[name] = info
+		var diffs []string
+		for _, key := range changedKeys {
+			want := changes[key].(map[string]string)["status"]
+			if have := statusOf(info.SecurityAndAnalysis, key); have != want {
+				diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", key, have, want))
+			}
+		}
+		if len(diffs) == 0 {
+			fmt.Printf("  %s: already up to date\n", name)
+			continue
+		}
+		fmt.Printf("  %s:\n", name)
+		for _, d := range diffs {
+			fmt.Printf("    > %s\n", d)
+		}
+		targets = append(targets, name)
+	}
+	if len(targets) == 0 {
+		fmt.Println("No changes needed.")
+		return
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d repositories would be updated.\n", len(targets))
+		return
+	}
+	if !*yes {
+		fmt.Printf("Apply these changes to %d repositories? (y/N): ", len(targets))
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if answer != "y\n" && answer != "Y\n" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"security_and_analysis": changes})
+	if err != nil {
+		log.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	failed := 0
+	actor := auditlog.Actor(client, apiBase, githubToken)
+	fmt.Println("--- Results (before -> after) ---")
+	for _, name := range targets {
+		after, err := doRepoRequest(client, "PATCH", apiBase, *org, name, githubToken, body)
+		entry := auditlog.Entry{
+			Actor:        actor,
+			Command:      "repo_security_settings",
+			Action:       "update_repo_security_settings",
+			Org:          *org,
+			Method:       "PATCH",
+			URL:          fmt.Sprintf("%s/repos/%s/%s", apiBase, *org, name),
+			Repositories: []string{name},
+			Before:       before[name].SecurityAndAnalysis,
+			After:        changes,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			auditlog.Append(auditlog.Path(*auditLog), entry)
+			fmt.Fprintf(os.Stderr, "  ❌ %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		entry.After = after.SecurityAndAnalysis
+		auditlog.Append(auditlog.Path(*auditLog), entry)
+		fmt.Printf("  ✅ %s\n", name)
+		for _, key := range changedKeys {
+			fmt.Printf("     %s: %s -> %s\n", key, statusOf(before[name].SecurityAndAnalysis, key), statusOf(after.SecurityAndAnalysis, key))
+		}
+	}
+	fmt.Printf("Updated %d of %d repositories.\n", len(targets)-failed, len(targets))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
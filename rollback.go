@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github-secret-scanning/internal/attachments"
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/ghapi"
+)
+
+// changeAttachment attaches ids to configID, or detaches them when configID is 0. It returns the HTTP status.
+func changeAttachment(client *http.Client, apiBase, org, token string, configID int, ids []int) (string, string, int, error) {
+	method := "POST"
+	url := fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/attach", apiBase, org, configID)
+	payload := map[string]interface{}{"scope": "selected", "selected_repository_ids": ids}
+	if configID == 0 {
+		method = "DELETE"
+		url = fmt.Sprintf("%s/orgs/%s/code-security/configurations/detach", apiBase, org)
+		payload = map[string]interface{}{"selected_repository_ids": ids}
+	}
+	status, _, err := ghapi.Send(client, method, url, token, payload)
+	return method, url, status, err
+}
+
+func describeConfig(name string, id int) string {
+	if id == 0 {
+		return "(none)"
+	}
+	if name == "" {
+		return fmt.Sprintf("#%d", id)
+	}
+	return name
+}
+
+func main() {
+	snapshotPath := flag.String("snapshot", "", "Snapshot written by add_repo_to_config (workspace/<org>-attach-snapshot-<time>.json)")
+	org := flag.String("org", "", "GitHub Organization name (default: the organization in the snapshot)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	dryRun := flag.Bool("dry-run", false, "Only show what would be restored")
+	yes := flag.Bool("yes", false, "Restore without asking for confirmation")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *snapshotPath == "" {
+		log.Fatal("Usage: go run rollback.go -snapshot workspace/<org>-attach-snapshot-<time>.json [-dry-run] [-yes]")
+	}
+
+	snapshot, err := attachments.ReadSnapshot(*snapshotPath)
+	if err != nil {
+		log.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if *org == "" {
+		*org = snapshot.Organization
+	} else if snapshot.Organization != "" && !strings.EqualFold(*org, snapshot.Organization) {
+		log.Fatalf("Snapshot is for organization '%s', not '%s'", snapshot.Organization, *org)
+	}
+	if *org == "" || len(snapshot.Repositories) == 0 {
+		log.Fatalf("Snapshot %s has no organization or no repositories", *snapshotPath)
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	var configs []map[string]interface{}
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", apiBase, *org), githubToken, &configs); err != nil {
+		log.Fatalf("Failed to read configurations: %v", err)
+	}
+	exists := make(map[int]bool)
+	for _, cfg := range configs {
+		if id, ok := cfg["id"].(float64); ok {
+			exists[int(id)] = true
+		}
+	}
+	current, err := attachments.Current(client, apiBase, *org, githubToken, configs)
+	if err != nil {
+		log.Fatalf("Failed to read current attachments: %v", err)
+	}
+
+	// Group the repositories that need to change by the configuration they go back to (0 = detach)
+	groups := make(map[int][]attachments.Repository)
+	var failures []string
+	unchanged := 0
+	fmt.Printf("--- Restoring attachments from %s (taken %s before attaching '%s') ---\n", *snapshotPath, snapshot.CreatedAt.Format("2006-01-02 15:04"), snapshot.Config)
+	for _, r := range snapshot.Repositories {
+		cur := current[r.ID]
+		if cur.ConfigurationID == r.ConfigurationID {
+			unchanged++
+			continue
+		}
+		if r.ConfigurationID != 0 && !exists[r.ConfigurationID] {
+			failures = append(failures, fmt.Sprintf("%s: configuration '%s' (ID %d) no longer exists", r.Name, r.Configuration, r.ConfigurationID))
+			continue
+		}
+		fmt.Printf("  %s: %s -> %s\n", r.Name, describeConfig(cur.Configuration, cur.ConfigurationID), describeConfig(r.Configuration, r.ConfigurationID))
+		groups[r.ConfigurationID] = append(groups[r.ConfigurationID], r)
+	}
+	if unchanged > 0 {
+		fmt.Printf("  %d repositories already match the snapshot\n", unchanged)
+	}
+	pending := 0
+	for _, repos := range groups {
+		pending += len(repos)
+	}
+	if pending == 0 {
+		reportRollback(0, 0, failures)
+		return
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d repositories would be restored.\n", pending)
+		if len(failures) > 0 {
+			reportRollback(0, 0, failures)
+		}
+		return
+	}
+	if !*yes {
+		fmt.Printf("Restore %d repositories? (y/N): ", pending)
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if answer != "y\n" && answer != "Y\n" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+
+	actor := auditlog.Actor(client, apiBase, githubToken)
+	audit := func(configID int, repos []attachments.Repository, method, url string, status int, err error) {
+		entry := auditlog.Entry{Actor: actor, Command: "rollback", Action: "attach", Org: *org, Method: method, URL: url, ConfigID: configID, Status: status}
+		if configID == 0 {
+			entry.Action = "detach"
+		}
+		before := make(map[string]string)
+		for _, r := range repos {
+			entry.Repositories = append(entry.Repositories, r.Name)
+			entry.RepositoryIDs = append(entry.RepositoryIDs, r.ID)
+			before[r.Name] = current[r.ID].Configuration
+			entry.Config = r.Configuration
+		}
+		entry.Before = map[string]interface{}{"configurations": before, "snapshot": *snapshotPath}
+		entry.After = map[string]interface{}{"configuration": entry.Config}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		auditlog.Append(auditlog.Path(*auditLog), entry)
+	}
+
+	var targets []int
+	for id := range groups {
+		targets = append(targets, id)
+	}
+	sort.Ints(targets)
+	restored := 0
+	for _, configID := range targets {
+		repos := groups[configID]
+		ids := make([]int, len(repos))
+		for i, r := range repos {
+			ids[i] = r.ID
+		}
+		method, url, status, err := changeAttachment(client, apiBase, *org, githubToken, configID, ids)
+		audit(configID, repos, method, url, status, err)
+		if err == nil {
+			fmt.Printf("  ✅ %s: %d repositories\n", describeConfig(repos[0].Configuration, configID), len(repos))
+			restored += len(repos)
+			continue
+		}
+		if len(repos) == 1 {
+			failures = append(failures, fmt.Sprintf("%s: %v", repos[0].Name, err))
+			continue
+		}
+		// One bad repository (e.g. deleted since the snapshot) fails the whole call, retry one by one to find it
+		fmt.Printf("  ⚠️  %s: %v, retrying one repository at a time\n", describeConfig(repos[0].Configuration, configID), err)
+		for _, r := range repos {
+			method, url, status, err := changeAttachment(client, apiBase, *org, githubToken, configID, []int{r.ID})
+			audit(configID, []attachments.Repository{r}, method, url, status, err)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", r.Name, err))
+				continue
+			}
+			restored++
+		}
+	}
+	reportRollback(restored, pending, failures)
+}
+
+// reportRollback prints the summary and exits with status 1 when a repository could not be restored.
+func reportRollback(restored, pending int, failures []string) {
+	if pending > 0 {
+		fmt.Printf("Restored %d of %d repositories.\n", restored, pending)
+	}
+	if len(failures) == 0 {
+		if pending == 0 {
+			fmt.Println("Nothing to restore, every repository matches the snapshot.")
+		}
+		return
+	}
+	fmt.Printf("\n❌ Could not restore %d repositories:\n", len(failures))
+	for _, f := range failures {
+		fmt.Printf("  %s\n", f)
+	}
+	os.Exit(1)
+}
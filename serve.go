@@ -0,0 +1,771 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/codesecurity"
+	"github-secret-scanning/internal/configyaml"
+	"github-secret-scanning/internal/ghapi"
+)
+
+// server holds what every handler needs to call the GitHub API.
+type server struct {
+	client       *http.Client
+	apiBase      string
+	token        string
+	defaultOrg   string
+	configDir    string
+	sessionToken string
+	auditLog     string
+	actor        string
+	allowedHosts map[string]bool
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// DiffEntry is one field that differs between the YAML file and the live configuration.
+type DiffEntry struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	Desired interface{} `json:"desired"`
+}
+
+// PlannedRepo is one repository in an attach/detach preview.
+type PlannedRepo struct {
+	Name                 string `json:"name"`
+	ID                   int    `json:"id,omitempty"`
+	CurrentConfiguration string `json:"current_configuration,omitempty"`
+	Error                string `json:"error,omitempty"`
+}
+
+type changeRequest struct {
+	Org          string   `json:"org"`
+	ConfigID     int      `json:"config_id"`
+	Repositories []string `json:"repositories"`
+	Confirm      bool     `json:"confirm"`
+}
+
+// github sends one request to the GitHub API and returns the status and body, also when GitHub answers with an error.
+func (s *server) github(method, url string, payload interface{}) (int, []byte, error) {
+	if !strings.HasPrefix(url, "http") {
+		url = s.apiBase + url
+	}
+	status, body, err := ghapi.Send(s.client, method, url, s.token, payload)
+	if status != 0 {
+		return status, body, nil
+	}
+	return 0, nil, err
+}
+
+// githubList follows Link headers and concatenates every page of a JSON array.
+func (s *server) githubList(path string) ([]json.RawMessage, error) {
+	var all []json.RawMessage
+	if err := ghapi.GetAll(s.client, s.apiBase+path, s.token, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, apiError{Error: fmt.Sprintf(format, args...)})
+}
+
+// namePattern is what GitHub allows in organization and repository names. Names from requests are checked before
+// they become part of an API path, so values like "../user" or "x?per_page" cannot reach other endpoints.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func validName(name string) bool {
+	return namePattern.MatchString(name) && name != "." && name != ".."
+}
+
+// orgParam returns the org query parameter, falling back to -org.
+func (s *server) orgParam(r *http.Request) string {
+	if org := strings.TrimSpace(r.URL.Query().Get("org")); org != "" {
+		return org
+	}
+	return s.defaultOrg
+}
+
+func (s *server) handleOrgs(w http.ResponseWriter, r *http.Request) {
+	items, err := s.githubList("/user/orgs?per_page=100")
+	orgs := []string{}
+	if s.defaultOrg != "" {
+		orgs = append(orgs, s.defaultOrg)
+	}
+	if err != nil {
+		// Fine-grained and GitHub App tokens cannot list memberships, the -org default still works
+		log.Printf("Listing organizations failed: %v", err)
+	}
+	for _, item := range items {
+		var o struct {
+			Login string `json:"login"`
+		}
+		if json.Unmarshal(item, &o) == nil && !strings.EqualFold(o.Login, s.defaultOrg) {
+			orgs = append(orgs, o.Login)
+		}
+	}
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+func (s *server) handleConfigurations(w http.ResponseWriter, r *http.Request) {
+	org := s.orgParam(r)
+	if !validName(org) {
+		writeError(w, http.StatusBadRequest, "a valid org is required")
+		return
+	}
+	items, err := s.githubList(fmt.Sprintf("/orgs/%s/code-security/configurations?per_page=100", org))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *server) handleConfiguration(w http.ResponseWriter, r *http.Request) {
+	org := s.orgParam(r)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || !validName(org) {
+		writeError(w, http.StatusBadRequest, "a valid org and a numeric configuration id are required")
+		return
+	}
+	status, body, err := s.github("GET", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, id), nil)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (s *server) handleConfigurationRepos(w http.ResponseWriter, r *http.Request) {
+	org := s.orgParam(r)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || !validName(org) {
+		writeError(w, http.StatusBadRequest, "a valid org and a numeric configuration id are required")
+		return
+	}
+	items, err := s.githubList(fmt.Sprintf("/orgs/%s/code-security/configurations/%d/repositories?per_page=100", org, id))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	type attached struct {
+		Name   string `json:"name"`
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	repos := []attached{}
+	for _, item := range items {
+		var a struct {
+			Status     string `json:"status"`
+			Repository struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"repository"`
+		}
+		if json.Unmarshal(item, &a) == nil {
+			repos = append(repos, attached{Name: a.Repository.Name, ID: a.Repository.ID, Status: a.Status})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	writeJSON(w, http.StatusOK, repos)
+}
+
+// yamlFiles lists the configuration YAML files offered for diffs.
+func (s *server) yamlFiles() []string {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(s.configDir, pattern))
+		for _, m := range matches {
+			files = append(files, filepath.Base(m))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (s *server) handleYAMLFiles(w http.ResponseWriter, r *http.Request) {
+	files := s.yamlFiles()
+	if files == nil {
+		files = []string{}
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	org := s.orgParam(r)
+	file := filepath.Base(r.URL.Query().Get("file"))
+	if !validName(org) || file == "." || file == "" {
+		writeError(w, http.StatusBadRequest, "a valid org and file are required")
+		return
+	}
+	known := false
+	for _, f := range s.yamlFiles() {
+		if f == file {
+			known = true
+		}
+	}
+	if !known {
+		writeError(w, http.StatusNotFound, "no YAML file '%s' in %s", file, s.configDir)
+		return
+	}
+	// Files are resolved as create/update would load them for this org
+	data, err := configyaml.Load(filepath.Join(s.configDir, file), map[string]string{"ORG": org})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	var desired codesecurity.Config
+	if err := yaml.Unmarshal(data, &desired); err != nil {
+		writeError(w, http.StatusBadRequest, "failed to parse %s: %v", file, err)
+		return
+	}
+	name := desired.Name
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "%s has no name", file)
+		return
+	}
+
+	var configs []codesecurity.Config
+	if err := ghapi.GetAll(s.client, fmt.Sprintf("%s/orgs/%s/code-security/configurations?per_page=100", s.apiBase, org), s.token, &configs); err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	var current codesecurity.Config
+	found := codesecurity.Find(configs, name)
+	if found != nil {
+		current = *found
+		// default_for_new_repos lives on the /defaults endpoint, not on the configuration itself
+		var defaults []codesecurity.Default
+		if err := ghapi.GetAll(s.client, fmt.Sprintf("%s/orgs/%s/code-security/configurations/defaults", s.apiBase, org), s.token, &defaults); err != nil {
+			writeError(w, http.StatusBadGateway, "%v", err)
+			return
+		}
+		current.DefaultForNewRepos = "none"
+		for _, d := range defaults {
+			if d.Configuration.ID == current.ID {
+				current.DefaultForNewRepos = d.DefaultForNewRepos
+			}
+		}
+	}
+
+	// The same comparison as update_org_config.go and config.go, so the UI shows what they would change
+	entries := []DiffEntry{}
+	for _, c := range codesecurity.Diff(current, desired) {
+		entries = append(entries, DiffEntry{Field: c.Field(), Current: c.Old, Desired: c.New})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"file":          file,
+		"configuration": name,
+		"exists":        found != nil,
+		"changes":       entries,
+	})
+}
+
+// planRepos resolves repository names to IDs and their current configuration.
+func (s *server) planRepos(org string, names []string) ([]PlannedRepo, []int) {
+	var plan []PlannedRepo
+	var ids []int
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p := PlannedRepo{Name: name}
+		if !validName(name) {
+			p.Error = "invalid repository name"
+			plan = append(plan, p)
+			continue
+		}
+		status, body, err := s.github("GET", fmt.Sprintf("/repos/%s/%s", org, name), nil)
+		if err != nil || status != http.StatusOK {
+			p.Error = "repository not found"
+			if err != nil {
+				p.Error = err.Error()
+			}
+			plan = append(plan, p)
+			continue
+		}
+		var repo struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &repo); err != nil || repo.ID == 0 {
+			p.Error = "unexpected repository response from GitHub"
+			if err != nil {
+				p.Error = fmt.Sprintf("failed to parse repository JSON: %v", err)
+			}
+			plan = append(plan, p)
+			continue
+		}
+		p.ID, p.Name = repo.ID, repo.Name
+		status, body, err = s.github("GET", fmt.Sprintf("/repos/%s/%s/code-security-configuration", org, repo.Name), nil)
+		if err == nil && status == http.StatusOK {
+			var current struct {
+				Configuration struct {
+					Name string `json:"name"`
+				} `json:"configuration"`
+			}
+			if json.Unmarshal(body, &current) == nil {
+				p.CurrentConfiguration = current.Configuration.Name
+			}
+		}
+		plan = append(plan, p)
+		ids = append(ids, p.ID)
+	}
+	return plan, ids
+}
+
+// allowedHosts returns the Host header values the server answers to: -addr and, on a loopback address, the other
+// names of the loopback interface, plus the host:port entries of -allowed-hosts.
+func allowedHosts(addr string, extra []string) (map[string]bool, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]bool)
+	ip := net.ParseIP(host)
+	switch {
+	case host == "localhost" || (ip != nil && ip.IsLoopback()):
+		for _, h := range []string{"localhost", "127.0.0.1", "::1", host} {
+			hosts[strings.ToLower(net.JoinHostPort(h, port))] = true
+		}
+	case host != "" && (ip == nil || !ip.IsUnspecified()):
+		hosts[strings.ToLower(net.JoinHostPort(host, port))] = true
+	}
+	for _, h := range extra {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, port)
+		}
+		hosts[strings.ToLower(h)] = true
+	}
+	return hosts, nil
+}
+
+// guard runs before every handler. The Host check stops DNS rebinding, where a web page points its own domain at
+// 127.0.0.1 to read the UI page and the session token in it; the Origin check stops other sites from posting changes.
+func (s *server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHosts[strings.ToLower(r.Host)] {
+			writeError(w, http.StatusMisdirectedRequest, "unexpected Host '%s', see -allowed-hosts", r.Host)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if origin := r.Header.Get("Origin"); origin != "" {
+				u, err := url.Parse(origin)
+				if err != nil || !s.allowedHosts[strings.ToLower(u.Host)] {
+					writeError(w, http.StatusForbidden, "cross-origin request from '%s' refused", origin)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkSession rejects mutating requests that do not carry the token embedded in the UI page.
+func (s *server) checkSession(w http.ResponseWriter, r *http.Request) bool {
+	got := r.Header.Get("X-Session-Token")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(s.sessionToken)) != 1 {
+		writeError(w, http.StatusForbidden, "missing or invalid X-Session-Token header")
+		return false
+	}
+	return true
+}
+
+func (s *server) handleChange(detach bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkSession(w, r) {
+			return
+		}
+		var req changeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: %v", err)
+			return
+		}
+		if req.Org == "" {
+			req.Org = s.defaultOrg
+		}
+		if !validName(req.Org) || len(req.Repositories) == 0 || (!detach && req.ConfigID == 0) {
+			writeError(w, http.StatusBadRequest, "a valid org, repositories and (for attach) config_id are required")
+			return
+		}
+		plan, ids := s.planRepos(req.Org, req.Repositories)
+		action := "attach"
+		if detach {
+			action = "detach"
+		}
+		if !req.Confirm || len(ids) == 0 {
+			// Preview only, the UI asks for confirmation and sends the same request with confirm=true
+			writeJSON(w, http.StatusOK, map[string]interface{}{"action": action, "applied": false, "repositories": plan})
+			return
+		}
+
+		entry := auditlog.Entry{Actor: s.actor, Command: "serve", Action: action, Org: req.Org, ConfigID: req.ConfigID, RepositoryIDs: ids}
+		before := make(map[string]string)
+		for _, p := range plan {
+			if p.ID != 0 {
+				entry.Repositories = append(entry.Repositories, p.Name)
+				before[p.Name] = p.CurrentConfiguration
+			}
+		}
+		entry.Before = map[string]interface{}{"configurations": before}
+
+		var status int
+		var body []byte
+		var err error
+		if detach {
+			entry.Method, entry.URL = "DELETE", fmt.Sprintf("%s/orgs/%s/code-security/configurations/detach", s.apiBase, req.Org)
+			entry.After = map[string]interface{}{"configuration": nil}
+			status, body, err = s.github("DELETE", entry.URL, map[string]interface{}{"selected_repository_ids": ids})
+		} else {
+			if st, cfg, err := s.github("GET", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", req.Org, req.ConfigID), nil); err == nil && st == http.StatusOK {
+				var c struct {
+					Name string `json:"name"`
+				}
+				json.Unmarshal(cfg, &c)
+				entry.Config = c.Name
+			}
+			entry.Method, entry.URL = "POST", fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/attach", s.apiBase, req.Org, req.ConfigID)
+			entry.After = map[string]interface{}{"configuration": entry.Config}
+			status, body, err = s.github("POST", entry.URL, map[string]interface{}{"scope": "selected", "selected_repository_ids": ids})
+		}
+		entry.Status = status
+		if err != nil {
+			entry.Error = err.Error()
+		} else if status < 200 || status >= 300 {
+			entry.Error = strings.TrimSpace(string(body))
+		}
+		auditlog.Append(s.auditLog, entry)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "%v", err)
+			return
+		}
+		if status < 200 || status >= 300 {
+			writeError(w, http.StatusBadGateway, "GitHub API error %d: %s", status, strings.TrimSpace(string(body)))
+			return
+		}
+		log.Printf("✅ %s %d repositories in '%s' (config %d)", action, len(ids), req.Org, req.ConfigID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"action": action, "applied": true, "repositories": plan})
+	}
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	indexTemplate.Execute(w, map[string]string{"Org": s.defaultOrg, "SessionToken": s.sessionToken})
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Code security configurations</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+  .row { display: flex; gap: 2rem; align-items: flex-start; }
+  .panel { border: 1px solid #d1d9e0; border-radius: 6px; padding: 1rem; margin-bottom: 1rem; }
+  #configs li { cursor: pointer; padding: 0.2rem 0; }
+  #configs li.selected { font-weight: 600; }
+  table { border-collapse: collapse; }
+  th, td { border: 1px solid #d1d9e0; padding: 0.3rem 0.6rem; text-align: left; }
+  th { background: #f6f8fa; }
+  textarea { width: 22rem; height: 6rem; }
+  .error { color: #d1242f; }
+  pre { background: #f6f8fa; padding: 0.6rem; max-height: 20rem; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>Code security configurations</h1>
+<div class="panel">
+  <label>Organization <input id="org" list="orgs" value="{{.Org}}"></label>
+  <datalist id="orgs"></datalist>
+  <button onclick="loadConfigs()">Load</button>
+  <span id="status"></span>
+</div>
+<div class="row">
+  <div class="panel"><h3>Configurations</h3><ul id="configs"></ul></div>
+  <div style="flex: 1">
+    <div class="panel" id="detail" hidden>
+      <h3 id="detail-name"></h3>
+      <details><summary>Settings</summary><pre id="detail-json"></pre></details>
+      <h4>Attached repositories</h4>
+      <table><thead><tr><th></th><th>Repository</th><th>Status</th></tr></thead><tbody id="repos"></tbody></table>
+      <p><button onclick="detachSelected()">Detach selected</button></p>
+      <h4>Attach repositories</h4>
+      <textarea id="attach-names" placeholder="One repository name per line"></textarea>
+      <p><button onclick="attachRepos()">Attach</button></p>
+    </div>
+    <div class="panel">
+      <h3>Diff against YAML</h3>
+      <select id="files"></select> <button onclick="diff()">Compare</button>
+      <div id="diff"></div>
+    </div>
+  </div>
+</div>
+<script>
+  var sessionToken = "{{.SessionToken}}";
+  var selected = null;
+
+  function org() { return document.getElementById("org").value.trim(); }
+  function setStatus(text, isError) {
+    var el = document.getElementById("status");
+    el.textContent = text;
+    el.className = isError ? "error" : "";
+  }
+  function api(path, options) {
+    return fetch(path, options).then(function (r) {
+      return r.json().then(function (body) {
+        if (!r.ok) { throw new Error(body.error || r.statusText); }
+        return body;
+      });
+    });
+  }
+  function cell(tr, text) { var td = document.createElement("td"); td.textContent = text; tr.appendChild(td); return td; }
+
+  function loadConfigs() {
+    setStatus("Loading...");
+    api("/api/configurations?org=" + encodeURIComponent(org())).then(function (configs) {
+      var ul = document.getElementById("configs");
+      ul.innerHTML = "";
+      configs.forEach(function (c) {
+        var li = document.createElement("li");
+        li.textContent = c.name + " (" + c.id + ")";
+        li.onclick = function () {
+          ul.querySelectorAll("li").forEach(function (x) { x.classList.remove("selected"); });
+          li.classList.add("selected");
+          showConfig(c);
+        };
+        ul.appendChild(li);
+      });
+      setStatus(configs.length + " configurations");
+    }).catch(function (e) { setStatus(e.message, true); });
+  }
+
+  function showConfig(c) {
+    selected = c;
+    document.getElementById("detail").hidden = false;
+    document.getElementById("detail-name").textContent = c.name;
+    document.getElementById("detail-json").textContent = JSON.stringify(c, null, 2);
+    loadRepos();
+  }
+
+  function loadRepos() {
+    api("/api/configurations/" + selected.id + "/repositories?org=" + encodeURIComponent(org())).then(function (repos) {
+      var tbody = document.getElementById("repos");
+      tbody.innerHTML = "";
+      repos.forEach(function (r) {
+        var tr = document.createElement("tr");
+        var box = document.createElement("input");
+        box.type = "checkbox";
+        box.value = r.name;
+        cell(tr, "").appendChild(box);
+        cell(tr, r.name);
+        cell(tr, r.status);
+        tbody.appendChild(tr);
+      });
+    }).catch(function (e) { setStatus(e.message, true); });
+  }
+
+  function describe(plan) {
+    return plan.repositories.map(function (r) {
+      if (r.error) { return "  " + r.name + ": " + r.error + " (skipped)"; }
+      return "  " + r.name + (r.current_configuration ? " (currently: " + r.current_configuration + ")" : "");
+    }).join("\n");
+  }
+
+  function change(action, names) {
+    var body = { org: org(), config_id: selected.id, repositories: names, confirm: false };
+    var post = function () {
+      return api("/api/" + action, {
+        method: "POST",
+        headers: { "Content-Type": "application/json", "X-Session-Token": sessionToken },
+        body: JSON.stringify(body)
+      });
+    };
+    post().then(function (plan) {
+      var target = action === "attach" ? " to '" + selected.name + "'" : "";
+      if (!confirm(action + " these repositories" + target + "?\n\n" + describe(plan))) { return; }
+      body.confirm = true;
+      return post().then(function () {
+        setStatus("✅ " + action + " requested");
+        loadRepos();
+      });
+    }).catch(function (e) { setStatus(e.message, true); });
+  }
+
+  function attachRepos() {
+    var names = document.getElementById("attach-names").value.split(/[\s,]+/).filter(Boolean);
+    if (names.length) { change("attach", names); }
+  }
+  function detachSelected() {
+    var names = Array.prototype.map.call(document.querySelectorAll("#repos input:checked"), function (b) { return b.value; });
+    if (names.length) { change("detach", names); }
+  }
+
+  function diff() {
+    var file = document.getElementById("files").value;
+    var out = document.getElementById("diff");
+    out.textContent = "Loading...";
+    api("/api/diff?org=" + encodeURIComponent(org()) + "&file=" + encodeURIComponent(file)).then(function (d) {
+      out.innerHTML = "";
+      var p = document.createElement("p");
+      p.textContent = d.exists ? "'" + d.configuration + "': " + d.changes.length + " field(s) differ"
+                               : "'" + d.configuration + "' does not exist yet in " + org();
+      out.appendChild(p);
+      if (!d.changes.length) { return; }
+      var table = document.createElement("table");
+      var head = document.createElement("tr");
+      ["Field", "Current", "YAML"].forEach(function (h) { var th = document.createElement("th"); th.textContent = h; head.appendChild(th); });
+      table.appendChild(head);
+      d.changes.forEach(function (c) {
+        var tr = document.createElement("tr");
+        cell(tr, c.field);
+        cell(tr, c.current === undefined || c.current === null ? "(not set)" : JSON.stringify(c.current));
+        cell(tr, c.desired === undefined || c.desired === null ? "(not set)" : JSON.stringify(c.desired));
+        table.appendChild(tr);
+      });
+      out.appendChild(table);
+    }).catch(function (e) { out.innerHTML = ""; setStatus(e.message, true); });
+  }
+
+  api("/api/orgs").then(function (orgs) {
+    var list = document.getElementById("orgs");
+    orgs.forEach(function (o) { var opt = document.createElement("option"); opt.value = o; list.appendChild(opt); });
+  });
+  api("/api/yaml-files").then(function (files) {
+    var select = document.getElementById("files");
+    files.forEach(function (f) { var opt = document.createElement("option"); opt.textContent = f; select.appendChild(opt); });
+  });
+  if (org()) { loadConfigs(); }
+</script>
+</body>
+</html>
+`))
+
+func main() {
+	org := flag.String("org", "", "Default GitHub Organization shown in the UI (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	addr := flag.String("addr", "127.0.0.1:8080", "Address to listen on")
+	configDir := flag.String("config-dir", "template", "Directory with configuration YAML files offered for diffs")
+	allowRemote := flag.Bool("allow-remote", false, "Allow listening on a non-loopback address")
+	extraHosts := flag.String("allowed-hosts", "", "Comma-separated host[:port] names the UI is reached under besides -addr (e.g. with -allow-remote on 0.0.0.0)")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of attach/detach changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	// The server acts with the token's permissions, so keep it on this machine unless asked otherwise
+	host, _, err := net.SplitHostPort(*addr)
+	if err != nil {
+		log.Fatalf("Invalid -addr '%s': %v", *addr, err)
+	}
+	if ip := net.ParseIP(host); !*allowRemote && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		log.Fatalf("Refusing to listen on non-loopback address '%s', pass -allow-remote to override", *addr)
+	}
+	hosts, err := allowedHosts(*addr, strings.Split(*extraHosts, ","))
+	if err != nil {
+		log.Fatalf("Invalid -addr '%s': %v", *addr, err)
+	}
+	if len(hosts) == 0 {
+		log.Fatalf("Listening on '%s' accepts any host name, list the ones the UI is reached under with -allowed-hosts", *addr)
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate session token: %v", err)
+	}
+	s := &server{
+		client:       &http.Client{},
+		apiBase:      apiBase,
+		token:        githubToken,
+		defaultOrg:   *org,
+		configDir:    *configDir,
+		sessionToken: hex.EncodeToString(secret),
+		auditLog:     auditlog.Path(*auditLog),
+		allowedHosts: hosts,
+	}
+	s.actor = auditlog.Actor(s.client, apiBase, githubToken)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", s.handleIndex)
+	mux.HandleFunc("GET /api/orgs", s.handleOrgs)
+	mux.HandleFunc("GET /api/configurations", s.handleConfigurations)
+	mux.HandleFunc("GET /api/configurations/{id}", s.handleConfiguration)
+	mux.HandleFunc("GET /api/configurations/{id}/repositories", s.handleConfigurationRepos)
+	mux.HandleFunc("GET /api/yaml-files", s.handleYAMLFiles)
+	mux.HandleFunc("GET /api/diff", s.handleDiff)
+	mux.HandleFunc("POST /api/attach", s.handleChange(false))
+	mux.HandleFunc("POST /api/detach", s.handleChange(true))
+
+	fmt.Printf("Serving on http://%s (Ctrl+C to stop)\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.guard(mux)))
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Snapshot is the subset of the metrics.go snapshot used for trends.
+type Snapshot struct {
+	Organization string    `json:"organization"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	TotalRepos   int       `json:"total_repos"`
+	Features     map[string]struct {
+		Enabled int     `json:"enabled"`
+		Percent float64 `json:"percent"`
+	} `json:"features"`
+	ConfigAttachments []struct {
+		Name     string `json:"name"`
+		Attached int    `json:"attached"`
+	} `json:"config_attachments"`
+	OpenAlerts map[string]struct {
+		Total int `json:"total"`
+	} `json:"open_alerts"`
+	Repositories []struct {
+		Name       string          `json:"name"`
+		Features   map[string]bool `json:"features"`
+		OpenAlerts map[string]int  `json:"open_alerts"`
+	} `json:"repositories"`
+}
+
+// Feature columns and their short headers, in display order.
+var featureColumns = []struct{ Key, Header string }{
+	{"advanced_security", "GHAS"},
+	{"secret_scanning", "SECRETS"},
+	{"secret_scanning_push_protection", "PUSH PROT"},
+	{"dependabot_security_updates", "DEP UPDATES"},
+	{"code_scanning_default_setup", "CS DEFAULT"},
+}
+
+var alertTypes = []string{"code_scanning", "secret_scanning", "dependabot"}
+
+// loadStore reads every snapshot for org from the JSONL history store, oldest first.
+func loadStore(path, org string) ([]Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(f)
+	// Snapshots carry one entry per repository, so lines can be long
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var s Snapshot
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if strings.EqualFold(s.Organization, org) {
+			snapshots = append(snapshots, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].GeneratedAt.Before(snapshots[j].GeneratedAt) })
+	return snapshots, nil
+}
+
+// weekly keeps the latest snapshot of each ISO week, returning at most the last n weeks.
+func weekly(snapshots []Snapshot, n int) []Snapshot {
+	var weeks []Snapshot
+	lastKey := ""
+	for _, s := range snapshots {
+		year, week := s.GeneratedAt.ISOWeek()
+		key := fmt.Sprintf("%d-W%02d", year, week)
+		if key == lastKey {
+			weeks[len(weeks)-1] = s
+			continue
+		}
+		weeks = append(weeks, s)
+		lastKey = key
+	}
+	if n > 0 && len(weeks) > n {
+		weeks = weeks[len(weeks)-n:]
+	}
+	return weeks
+}
+
+// baselineFor returns the newest snapshot at least period older than latest, or the oldest one.
+func baselineFor(snapshots []Snapshot, latest Snapshot, period time.Duration) Snapshot {
+	baseline := snapshots[0]
+	for _, s := range snapshots {
+		if latest.GeneratedAt.Sub(s.GeneratedAt) >= period {
+			baseline = s
+		}
+	}
+	return baseline
+}
+
+func signed(f float64) string {
+	if f > 0 {
+		return "+" + strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
+
+func signedInt(n int) string {
+	if n > 0 {
+		return fmt.Sprintf("+%d", n)
+	}
+	return strconv.Itoa(n)
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	storePath := flag.String("store", "workspace/metrics-history.jsonl", "History store written by metrics.go")
+	weeks := flag.Int("weeks", 6, "Number of weeks shown in the week-over-week table")
+	period := flag.String("period", "7d", "Compare the latest snapshot with the newest one at least this old (e.g. 7d, 30d)")
+	failOnRegression := flag.Bool("fail-on-regression", false, "Exit with status 1 when a repository lost a feature")
+	flag.Parse()
+
+	if *org == "" {
+		log.Fatal("Usage: go run trend.go -org <org> [-store workspace/metrics-history.jsonl] [-weeks 6] [-period 7d] [-fail-on-regression]")
+	}
+	days := strings.TrimSuffix(*period, "d")
+	periodDays, err := strconv.Atoi(days)
+	if err != nil || periodDays <= 0 || !strings.HasSuffix(*period, "d") {
+		log.Fatalf("Invalid -period '%s': use a number of days like 7d", *period)
+	}
+
+	snapshots, err := loadStore(*storePath, *org)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *storePath, err)
+	}
+	if len(snapshots) == 0 {
+		log.Fatalf("No snapshots for '%s' in %s, run metrics.go first", *org, *storePath)
+	}
+
+	// 1. Week-over-week table
+	points := weekly(snapshots, *weeks)
+	fmt.Printf("Week-over-week for '%s' (%d snapshots in store)\n\n", *org, len(snapshots))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"WEEK", "DATE", "REPOS"}
+	for _, c := range featureColumns {
+		header = append(header, c.Header+" %")
+	}
+	for _, t := range alertTypes {
+		header = append(header, strings.ToUpper(strings.ReplaceAll(t, "_", " ")))
+	}
+	fmt.Fprintln(w, "  "+strings.Join(header, "\t"))
+	for i, s := range points {
+		year, week := s.GeneratedAt.ISOWeek()
+		row := []string{fmt.Sprintf("%d-W%02d", year, week), s.GeneratedAt.Format("2006-01-02"), strconv.Itoa(s.TotalRepos)}
+		for _, c := range featureColumns {
+			cell := "-"
+			if f, ok := s.Features[c.Key]; ok {
+				cell = strconv.FormatFloat(f.Percent, 'f', 1, 64)
+				if i > 0 {
+					if prev, ok := points[i-1].Features[c.Key]; ok && prev.Percent != f.Percent {
+						cell += " (" + signed(f.Percent-prev.Percent) + ")"
+					}
+				}
+			}
+			row = append(row, cell)
+		}
+		for _, t := range alertTypes {
+			cell := "-"
+			if a, ok := s.OpenAlerts[t]; ok {
+				cell = strconv.Itoa(a.Total)
+				if i > 0 {
+					if prev, ok := points[i-1].OpenAlerts[t]; ok && prev.Total != a.Total {
+						cell += " (" + signedInt(a.Total-prev.Total) + ")"
+					}
+				}
+			}
+			row = append(row, cell)
+		}
+		fmt.Fprintln(w, "  "+strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	latest := snapshots[len(snapshots)-1]
+	baseline := baselineFor(snapshots, latest, time.Duration(periodDays)*24*time.Hour)
+	if baseline.GeneratedAt.Equal(latest.GeneratedAt) {
+		fmt.Println("\nOnly one snapshot in the store, nothing to compare yet.")
+		return
+	}
+	fmt.Printf("\nChanges from %s to %s\n", baseline.GeneratedAt.Format("2006-01-02 15:04"), latest.GeneratedAt.Format("2006-01-02 15:04"))
+
+	// 2. Configuration attachments
+	before := make(map[string]int)
+	for _, c := range baseline.ConfigAttachments {
+		before[c.Name] = c.Attached
+	}
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\n  CONFIGURATION\tATTACHED\tCHANGE")
+	for _, c := range latest.ConfigAttachments {
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", c.Name, c.Attached, signedInt(c.Attached-before[c.Name]))
+		delete(before, c.Name)
+	}
+	deleted := make([]string, 0, len(before))
+	for name := range before {
+		deleted = append(deleted, name)
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		fmt.Fprintf(w, "  %s (deleted)\t0\t%s\n", name, signedInt(-before[name]))
+	}
+	w.Flush()
+
+	// 3. Per-repository regressions
+	type repoState struct {
+		features map[string]bool
+		alerts   map[string]int
+	}
+	old := make(map[string]repoState)
+	for _, r := range baseline.Repositories {
+		old[r.Name] = repoState{r.Features, r.OpenAlerts}
+	}
+	var regressions, improvements, alertIncreases, added []string
+	for _, r := range latest.Repositories {
+		prev, ok := old[r.Name]
+		if !ok {
+			added = append(added, r.Name)
+			continue
+		}
+		delete(old, r.Name)
+		for _, c := range featureColumns {
+			was, had := prev.features[c.Key]
+			now, has := r.Features[c.Key]
+			if !had || !has {
+				// Feature was not collected in one of the snapshots
+				continue
+			}
+			if was && !now {
+				regressions = append(regressions, fmt.Sprintf("%s: %s turned off", r.Name, c.Key))
+			} else if !was && now {
+				improvements = append(improvements, fmt.Sprintf("%s: %s turned on", r.Name, c.Key))
+			}
+		}
+		for _, t := range alertTypes {
+			if d := r.OpenAlerts[t] - prev.alerts[t]; d > 0 {
+				alertIncreases = append(alertIncreases, fmt.Sprintf("%s: %s open alerts %d -> %d", r.Name, t, prev.alerts[t], r.OpenAlerts[t]))
+			}
+		}
+	}
+	var removed []string
+	for name := range old {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+
+	printList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Printf("\n%s (%d)\n", title, len(items))
+		for _, item := range items {
+			fmt.Printf("  %s\n", item)
+		}
+	}
+	if len(regressions) == 0 {
+		fmt.Println("\n✅ No repository lost a security feature")
+	} else {
+		printList("❌ Regressions", regressions)
+	}
+	printList("⚠️  Open alerts increased", alertIncreases)
+	printList("✅ Features turned on", improvements)
+	printList("New repositories", added)
+	printList("Repositories no longer present", removed)
+
+	if *failOnRegression && len(regressions) > 0 {
+		os.Exit(1)
+	}
+}
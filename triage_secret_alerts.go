@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/ghapi"
+)
+
+// TriageRule resolves open alerts matching every non-empty criterion.
+type TriageRule struct {
+	Name        string   `yaml:"name"`
+	Repos       []string `yaml:"repos"`
+	SecretTypes []string `yaml:"secret_types"`
+	Paths       []string `yaml:"paths"`
+	Validity    []string `yaml:"validity"`
+	Resolution  string   `yaml:"resolution"`
+	Comment     string   `yaml:"comment"`
+
+	pathPatterns []*regexp.Regexp
+}
+
+type TriageRulesFile struct {
+	Rules []TriageRule `yaml:"rules"`
+}
+
+type SecretScanningAlert struct {
+	Number     int    `json:"number"`
+	State      string `json:"state"`
+	SecretType string `json:"secret_type"`
+	Validity   string `json:"validity"`
+	HTMLURL    string `json:"html_url"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+type alertLocation struct {
+	Type    string `json:"type"`
+	Details struct {
+		Path string `json:"path"`
+	} `json:"details"`
+}
+
+// nonFileLocation prefixes the locations fetchAlertPaths returns for places other than a file in the repository:
+// issues, pull requests, discussions, wiki pages. They are public, so no path pattern matches them.
+const nonFileLocation = "\x00non-file:"
+
+var validResolutions = map[string]bool{
+	"false_positive": true,
+	"used_in_tests":  true,
+	"wont_fix":       true,
+	"revoked":        true,
+}
+
+// globToRegexp converts a path glob to a regexp; "**" matches across directories.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" also matches zero directories
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func loadTriageRules(path string) ([]TriageRule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	var file TriageRulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("rules file '%s' contains no rules", path)
+	}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !validResolutions[r.Resolution] {
+			return nil, fmt.Errorf("rule '%s': resolution must be one of false_positive, used_in_tests, wont_fix, revoked", r.Name)
+		}
+		if len(r.Repos) == 0 && len(r.SecretTypes) == 0 && len(r.Paths) == 0 && len(r.Validity) == 0 {
+			return nil, fmt.Errorf("rule '%s' has no match criteria and would resolve every alert", r.Name)
+		}
+		for _, g := range r.Repos {
+			if _, err := filepath.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("rule '%s': invalid repo pattern '%s': %w", r.Name, g, err)
+			}
+		}
+		for _, p := range r.Paths {
+			re, err := globToRegexp(p)
+			if err != nil {
+				return nil, fmt.Errorf("rule '%s': invalid path pattern '%s': %w", r.Name, p, err)
+			}
+			r.pathPatterns = append(r.pathPatterns, re)
+		}
+	}
+	return file.Rules, nil
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWithoutPaths checks the criteria that need no extra API call.
+func (r TriageRule) matchesWithoutPaths(a SecretScanningAlert) bool {
+	if len(r.Repos) > 0 {
+		found := false
+		for _, g := range r.Repos {
+			if ok, _ := filepath.Match(g, a.Repository.Name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.SecretTypes) > 0 && !containsFold(r.SecretTypes, a.SecretType) {
+		return false
+	}
+	if len(r.Validity) > 0 && !containsFold(r.Validity, a.Validity) {
+		return false
+	}
+	return true
+}
+
+// matchesPaths requires every location of the alert to match one of the patterns,
+// so a secret that also leaked outside test fixtures, or outside the code, is left open.
+func (r TriageRule) matchesPaths(paths []string) bool {
+	if len(r.pathPatterns) == 0 {
+		return true
+	}
+	if len(paths) == 0 {
+		return false
+	}
+	for _, p := range paths {
+		if strings.HasPrefix(p, nonFileLocation) {
+			return false
+		}
+		matched := false
+		for _, re := range r.pathPatterns {
+			if re.MatchString(p) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func fetchOpenAlerts(client *http.Client, apiBase, org, token string) ([]SecretScanningAlert, error) {
+	var alerts []SecretScanningAlert
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("hide_secret", "true")
+	query.Set("per_page", "100")
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/secret-scanning/alerts?%s", apiBase, org, query.Encode()), token, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// fetchAlertPaths returns the file paths of an alert's locations, and a nonFileLocation entry for each location
+// outside the repository files.
+func fetchAlertPaths(client *http.Client, apiBase, org, repo, token string, number int) ([]string, error) {
+	var locations []alertLocation
+	if err := ghapi.GetAll(client, fmt.Sprintf("%s/repos/%s/%s/secret-scanning/alerts/%d/locations?per_page=100", apiBase, org, repo, number), token, &locations); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, l := range locations {
+		// Wiki commits have a path too, but the page is not part of the code
+		if (l.Type == "commit" || l.Type == "") && l.Details.Path != "" {
+			paths = append(paths, l.Details.Path)
+		} else {
+			paths = append(paths, nonFileLocation+l.Type)
+		}
+	}
+	return paths, nil
+}
+
+type plannedResolution struct {
+	Alert SecretScanningAlert
+	Rule  TriageRule
+	Paths []string
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
+	token := flag.String("token", "", "GitHub API token")
+	ghesURL := flag.String("ghes-url", "", "GitHub Enterprise Server URL (if using GHES)")
+	rulesPath := flag.String("rules", "", "Path to the YAML triage rules file")
+	dryRun := flag.Bool("dry-run", false, "List every alert that would be resolved without changing it")
+	yes := flag.Bool("yes", false, "Resolve without asking for confirmation")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	flag.Parse()
+
+	// GHES_URL env var fallback
+	if *ghesURL == "" {
+		if envURL := os.Getenv("GHES_URL"); envURL != "" {
+			*ghesURL = strings.TrimRight(envURL, "/")
+		}
+	}
+
+	githubToken := *token
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN_ORG")
+	}
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if githubToken == "" {
+		log.Fatal("GitHub token must be provided via -token flag or GITHUB_TOKEN_ORG / GITHUB_TOKEN environment variable")
+	}
+	if *org == "" || *rulesPath == "" {
+		log.Fatal("Usage: go run triage_secret_alerts.go -org <org> -rules rules.yaml [-dry-run] [-yes]")
+	}
+
+	rules, err := loadTriageRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
+	var apiBase string
+	switch githubEndpoint {
+	case "GHEC":
+		apiBase = "https://api.github.com"
+	case "GHES", "":
+		if *ghesURL == "" {
+			log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES")
+		}
+		apiBase = *ghesURL
+	default:
+		log.Fatalf("GITHUB_ENDPOINT environment variable must be set either to GHEC or GHES. Got '%s'", githubEndpoint)
+	}
+
+	client := &http.Client{}
+	alerts, err := fetchOpenAlerts(client, apiBase, *org, githubToken)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Printf("Evaluating %d open secret scanning alerts against %d rules\n", len(alerts), len(rules))
+
+	var plan []plannedResolution
+	for _, a := range alerts {
+		var paths []string
+		pathsLoaded := false
+		for _, rule := range rules {
+			if !rule.matchesWithoutPaths(a) {
+				continue
+			}
+			if len(rule.pathPatterns) > 0 && !pathsLoaded {
+				paths, err = fetchAlertPaths(client, apiBase, *org, a.Repository.Name, githubToken, a.Number)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  ⚠️  %s#%d: could not read locations: %v\n", a.Repository.Name, a.Number, err)
+					break
+				}
+				pathsLoaded = true
+			}
+			if !rule.matchesPaths(paths) {
+				continue
+			}
+			// First matching rule wins
+			plan = append(plan, plannedResolution{Alert: a, Rule: rule, Paths: paths})
+			break
+		}
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("No alerts match the rules.")
+		return
+	}
+	fmt.Println("--- Alerts to resolve ---")
+	for _, p := range plan {
+		fmt.Printf("  %s#%d %s (validity: %s) -> %s [rule: %s]\n", p.Alert.Repository.Name, p.Alert.Number, p.Alert.SecretType, p.Alert.Validity, p.Rule.Resolution, p.Rule.Name)
+		for _, path := range p.Paths {
+			fmt.Printf("      %s\n", path)
+		}
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d alerts would be resolved.\n", len(plan))
+		return
+	}
+	if !*yes {
+		fmt.Printf("Resolve these %d alerts? (y/N): ", len(plan))
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if answer != "y\n" && answer != "Y\n" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+
+	failed := 0
+	actor := auditlog.Actor(client, apiBase, githubToken)
+	for _, p := range plan {
+		alertURL := fmt.Sprintf("%s/repos/%s/%s/secret-scanning/alerts/%d", apiBase, *org, p.Alert.Repository.Name, p.Alert.Number)
+		payload := map[string]string{
+			"state":      "resolved",
+			"resolution": p.Rule.Resolution,
+		}
+		if p.Rule.Comment != "" {
+			payload["resolution_comment"] = p.Rule.Comment
+		}
+		status, _, err := ghapi.Send(client, "PATCH", alertURL, githubToken, payload)
+		entry := auditlog.Entry{
+			Actor:        actor,
+			Command:      "triage_secret_alerts",
+			Action:       "resolve_secret_alert",
+			Org:          *org,
+			Method:       "PATCH",
+			URL:          alertURL,
+			Repositories: []string{p.Alert.Repository.Name},
+			Before:       map[string]interface{}{"number": p.Alert.Number, "state": "open", "secret_type": p.Alert.SecretType, "validity": p.Alert.Validity},
+			After:        payload,
+		}
+		entry.Status = status
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		auditlog.Append(auditlog.Path(*auditLog), entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ❌ %s#%d: %v\n", p.Alert.Repository.Name, p.Alert.Number, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  ✅ %s#%d resolved as %s\n", p.Alert.Repository.Name, p.Alert.Number, p.Rule.Resolution)
+	}
+	fmt.Printf("Resolved %d of %d alerts.\n", len(plan)-failed, len(plan))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -14,59 +14,37 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
-)
-
-// Reviewer represents a reviewer for delegated bypass
-type Reviewer struct {
-	ReviewerID   int    `yaml:"reviewer_id" json:"reviewer_id"`
-	ReviewerType string `yaml:"reviewer_type" json:"reviewer_type"`
-}
-
-// SecretScanningDelegatedBypassOptions represents the options for delegated bypass
-type SecretScanningDelegatedBypassOptions struct {
-	Reviewers []Reviewer `yaml:"reviewers" json:"reviewers"`
-}
-
-type DependencyGraphAutosubmitActionOptions struct {
-	LabeledRunners bool `yaml:"labeled_runners" json:"labeled_runners"`
-}
-
-type CodeScanningDefaultSetupOptions struct {
-	RunnerType  string      `yaml:"runner_type" json:"runner_type"`
-	RunnerLabel interface{} `yaml:"runner_label" json:"runner_label"`
-}
 
-type CodeSecurityConfig struct {
-	ID int `json:"id"`
-	Name                               string `yaml:"name" json:"name"`
-	Description                        string `yaml:"description" json:"description"`
-	AdvancedSecurity                   string `yaml:"advanced_security" json:"advanced_security"`
-	DependencyGraph                    string `yaml:"dependency_graph" json:"dependency_graph"`
-	DependencyGraphAutosubmitAction    string `yaml:"dependency_graph_autosubmit_action" json:"dependency_graph_autosubmit_action"`
-	DependencyGraphAutosubmitActionOptions DependencyGraphAutosubmitActionOptions `yaml:"dependency_graph_autosubmit_action_options" json:"dependency_graph_autosubmit_action_options"`
-	DependabotAlerts                   string `yaml:"dependabot_alerts" json:"dependabot_alerts"`
-	DependabotSecurityUpdates          string `yaml:"dependabot_security_updates" json:"dependabot_security_updates"`
-	CodeScanningDefaultSetup           string `yaml:"code_scanning_default_setup" json:"code_scanning_default_setup"`
-	CodeScanningDefaultSetupOptions    CodeScanningDefaultSetupOptions `yaml:"code_scanning_default_setup_options" json:"code_scanning_default_setup_options"`
-	SecretScanning                     string `yaml:"secret_scanning" json:"secret_scanning"`
-	SecretScanningPushProtection       string `yaml:"secret_scanning_push_protection" json:"secret_scanning_push_protection"`
-	SecretScanningValidityChecks       string `yaml:"secret_scanning_validity_checks" json:"secret_scanning_validity_checks"`
-	SecretScanningNonProviderPatterns  string `yaml:"secret_scanning_non_provider_patterns" json:"secret_scanning_non_provider_patterns"`
-	SecretScanningGenericSecrets       string `yaml:"secret_scanning_generic_secrets" json:"secret_scanning_generic_secrets"`
-	SecretScanningDelegatedBypass      string `yaml:"secret_scanning_delegated_bypass" json:"secret_scanning_delegated_bypass"`
-	SecretScanningDelegatedBypassOptions SecretScanningDelegatedBypassOptions `yaml:"secret_scanning_delegated_bypass_options" json:"secret_scanning_delegated_bypass_options"`
-	SecretScanningDelegatedAlertDismissal string `yaml:"secret_scanning_delegated_alert_dismissal" json:"secret_scanning_delegated_alert_dismissal"`
-	PrivateVulnerabilityReporting      string `yaml:"private_vulnerability_reporting" json:"private_vulnerability_reporting"`
-	Enforcement                        string `yaml:"enforcement" json:"enforcement"`
-	DefaultForNewRepos                bool   `yaml:"default_for_new_repos" json:"default_for_new_repos"`
-}
+	"github-secret-scanning/internal/auditlog"
+	"github-secret-scanning/internal/codesecurity"
+	"github-secret-scanning/internal/configyaml"
+	"github-secret-scanning/internal/ghapi"
+)
 
 func main() {
 	yamlPath := flag.String("yaml", "", "Path to YAML file with new configuration")
 	tokenFlag := flag.String("token", "", "GitHub API token")
 	org := flag.String("org", "", "GitHub Organization name (e.g. my-org)")
 	ghesURL := flag.String("ghes-url", "", "Base URL for GHES api (ignored for GHEC)")
+	auditLog := flag.String("audit-log", "", "JSONL audit log of changes (default: AUDIT_LOG or workspace/audit.jsonl)")
+	historyDir := flag.String("history-dir", "workspace/.history", "Directory the previous remote state is saved to before each update")
+	policyPath := flag.String("policy", "", "Guardrails file checked against the diff (default: UPDATE_POLICY or workspace/update_policy.yaml, when it exists)")
+	overridePolicy := flag.String("override-policy", "", "Apply despite policy violations; the reason is written to the audit log")
+	noPolicy := flag.Bool("no-policy", false, "Do not check a policy; needed for -yes when no policy file exists")
+	yes := flag.Bool("yes", false, "Apply without asking for confirmation (needs a policy or -no-policy; policy violations still block)")
+	diffFormat := flag.String("diff-format", "text", "Diff format: text (unified), json (JSON patch) or markdown (table for pull request comments)")
+	diffOutput := flag.String("diff-output", "", "Write the diff to this file instead of stdout")
+	diffOnly := flag.Bool("diff-only", false, "Only show the diff, do not apply it")
+	colorMode := flag.String("color", "auto", "Color the text diff: auto, always or never")
+	vars := configyaml.Vars{}
+	flag.Var(vars, "var", "Variable for ${NAME} references in the YAML as NAME=value (repeatable, ${ORG} defaults to -org)")
 	flag.Parse()
+	if _, ok := vars["ORG"]; !ok && *org != "" {
+		vars["ORG"] = *org
+	}
+	if *diffFormat != "text" && *diffFormat != "json" && *diffFormat != "markdown" {
+		log.Fatalf("Invalid -diff-format '%s': must be text, json or markdown", *diffFormat)
+	}
 
 	// GHES_URL env var fallback
 	if *ghesURL == "" {
@@ -93,125 +71,155 @@ func main() {
 	}
 
 	// Read new config from YAML
-	data, err := ioutil.ReadFile(*yamlPath)
+	data, err := configyaml.Load(*yamlPath, vars)
 	if err != nil {
 		log.Fatalf("Failed to read YAML file: %v", err)
 	}
-	var newConfig CodeSecurityConfig
+	var newConfig codesecurity.Config
 	if err := yaml.Unmarshal(data, &newConfig); err != nil {
 		log.Fatalf("Failed to parse YAML: %v", err)
 	}
 
 	// Read current config from GitHub
 	githubEndpoint := os.Getenv("GITHUB_ENDPOINT")
-	var url string
+	var url, apiBase string
 	switch githubEndpoint {
 	case "GHEC":
+		apiBase = "https://api.github.com"
 		url = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations", *org)
 	case "GHES":
 		if *ghesURL == "" { log.Fatal("Set -ghes-url or GHES_URL when GITHUB_ENDPOINT=GHES") }
+		apiBase = *ghesURL
 		url = fmt.Sprintf("%s/orgs/%s/code-security/configurations", *ghesURL, *org)
 	default:
 		log.Fatalf("GITHUB_ENDPOINT environment variable must be set to either GHEC or GHES, or left unset for GHES as default")
 	}	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", "Bearer "+*token)
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("Request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		log.Fatalf("API error: %s\n%s\n", resp.Status, string(body))
-	}
-	body, _ := ioutil.ReadAll(resp.Body)
-	var currentConfigs []CodeSecurityConfig
-	if err := json.Unmarshal(body, &currentConfigs); err != nil {
-		log.Fatalf("Failed to parse current config JSON (expected array): %v", err)
+	var currentConfigs []codesecurity.Config
+	if err := ghapi.GetAll(client, url, token, &currentConfigs); err != nil {
+		log.Fatalf("Failed to read current configurations: %v", err)
 	}
 	if len(currentConfigs) == 0 {
 		fmt.Println("No current configuration found in GitHub API response.")
 	}
 	// Find the config with the same name as newConfig for diff and update
-	var currentConfig CodeSecurityConfig
+	var currentConfig codesecurity.Config
 	for _, cfg := range currentConfigs {
 		if cfg.Name == newConfig.Name {
 			currentConfig = cfg
 			break
 		}
 	}
+	// default_for_new_repos is not part of the configuration, GitHub keeps it on the /defaults endpoint
+	if currentConfig.ID != 0 {
+		var defaults []codesecurity.Default
+		if err := ghapi.GetAll(client, fmt.Sprintf("%s/orgs/%s/code-security/configurations/defaults", apiBase, *org), token, &defaults); err != nil {
+			log.Fatalf("Failed to read default configurations: %v", err)
+		}
+		currentConfig.DefaultForNewRepos = "none"
+		for _, d := range defaults {
+			if d.Configuration.ID == currentConfig.ID {
+				currentConfig.DefaultForNewRepos = d.DefaultForNewRepos
+			}
+		}
+	}
 
 
 	// Show diff and highlight changes
-	fmt.Println("--- Diff (lines starting with '>' are changed) ---")
-	hasChanges := printYAMLDiff(currentConfig, newConfig)
-	if !hasChanges {
-		fmt.Println("No changes detected.")
+	changes := codesecurity.Diff(currentConfig, newConfig)
+	var configChanged, defaultChanged bool
+	for _, c := range changes {
+		if c.Path[0] == "default_for_new_repos" {
+			defaultChanged = true
+		} else {
+			configChanged = true
+		}
+	}
+	from := fmt.Sprintf("%s/%s on GitHub", *org, newConfig.Name)
+	if currentConfig.Name == "" {
+		from += " (does not exist yet)"
+	}
+	var diff bytes.Buffer
+	if err := codesecurity.WriteDiff(&diff, *diffFormat, changes, from, *yamlPath, *diffOutput == "" && codesecurity.UseColor(*colorMode)); err != nil {
+		log.Fatalf("Failed to render diff: %v", err)
+	}
+	if *diffOutput != "" {
+		if err := ioutil.WriteFile(*diffOutput, diff.Bytes(), 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", *diffOutput, err)
+		}
+		fmt.Printf("📝 Diff (%s, %d change(s)) written to %s\n", *diffFormat, len(changes), *diffOutput)
+	} else {
+		os.Stdout.Write(diff.Bytes())
+	}
+
+	var policyRequired bool
+	*policyPath, policyRequired = codesecurity.PolicyPath(*policyPath)
+	if *noPolicy && policyRequired {
+		log.Fatal("-policy and -no-policy cannot be combined")
+	}
+	var policy *codesecurity.Policy
+	if !*noPolicy {
+		policy, err = codesecurity.LoadPolicy(*policyPath, policyRequired)
+		if err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+	}
+	// Unattended updates are only safe with guardrails, unless they are explicitly turned off
+	if *yes && !*diffOnly && policy == nil && !*noPolicy {
+		log.Fatalf("-yes needs a policy and none was found at %s. Pass -policy <file>, or -no-policy to apply without one", *policyPath)
+	}
+	violations := policy.Check(changes)
+	if *diffOnly {
+		// Keep a JSON diff on stdout parseable
+		out := os.Stdout
+		if *diffFormat != "text" && *diffOutput == "" {
+			out = os.Stderr
+		}
+		if len(violations) > 0 {
+			fmt.Fprintf(out, "\n--- Policy violations (%s) ---\n", *policyPath)
+			for _, v := range violations {
+				fmt.Fprintf(out, "🚫 %s\n", v)
+			}
+		} else if policy != nil && len(changes) > 0 {
+			fmt.Fprintf(out, "✅ Policy %s: no violations\n", *policyPath)
+		}
 		return
 	}
-	fmt.Print("Apply these changes? (y/N): ")
-	reader := bufio.NewReader(os.Stdin)
-	answer, _ := reader.ReadString('\n')
-	if answer != "y\n" && answer != "Y\n" {
-		fmt.Println("Aborted.")
+	if len(changes) == 0 {
+		if *diffFormat != "text" || *diffOutput != "" {
+			fmt.Println("No changes detected.")
+		}
 		return
 	}
 
-
-	// Remove ID from request body (must not be sent)
-	type CodeSecurityConfigRequest struct {
-		Name                               string      `json:"name"`
-		Description                        string      `json:"description"`
-		AdvancedSecurity                   string      `json:"advanced_security"`
-		DependencyGraph                    string      `json:"dependency_graph"`
-		DependencyGraphAutosubmitAction    string      `json:"dependency_graph_autosubmit_action"`
-		DependencyGraphAutosubmitActionOptions DependencyGraphAutosubmitActionOptions `json:"dependency_graph_autosubmit_action_options"`
-		DependabotAlerts                   string      `json:"dependabot_alerts"`
-		DependabotSecurityUpdates          string      `json:"dependabot_security_updates"`
-		CodeScanningDefaultSetup           string      `json:"code_scanning_default_setup"`
-		CodeScanningDefaultSetupOptions    CodeScanningDefaultSetupOptions `json:"code_scanning_default_setup_options"`
-		SecretScanning                     string      `json:"secret_scanning"`
-		SecretScanningPushProtection       string      `json:"secret_scanning_push_protection"`
-		SecretScanningValidityChecks       string      `json:"secret_scanning_validity_checks"`
-		SecretScanningNonProviderPatterns  string      `json:"secret_scanning_non_provider_patterns"`
-		SecretScanningGenericSecrets       string      `json:"secret_scanning_generic_secrets"`
-		SecretScanningDelegatedBypass      string      `json:"secret_scanning_delegated_bypass"`
-		SecretScanningDelegatedBypassOptions SecretScanningDelegatedBypassOptions `json:"secret_scanning_delegated_bypass_options"`
-		SecretScanningDelegatedAlertDismissal string `json:"secret_scanning_delegated_alert_dismissal"`
-		PrivateVulnerabilityReporting      string      `json:"private_vulnerability_reporting"`
-		Enforcement                        string      `json:"enforcement"`
-		DefaultForNewRepos                bool        `json:"default_for_new_repos"`
+	if len(violations) > 0 {
+		fmt.Printf("\n--- Policy violations (%s) ---\n", *policyPath)
+		for _, v := range violations {
+			fmt.Printf("🚫 %s\n", v)
+		}
+		if strings.TrimSpace(*overridePolicy) == "" {
+			fmt.Fprintln(os.Stderr, "Update blocked by policy. Fix the YAML, or re-run with -override-policy \"<reason>\" to apply it anyway.")
+			os.Exit(1)
+		}
+		fmt.Printf("⚠️  Overriding the policy: %s\n", *overridePolicy)
+	} else if policy != nil {
+		fmt.Printf("✅ Policy %s: no violations\n", *policyPath)
 	}
-	reqBody := CodeSecurityConfigRequest{
-		Name: newConfig.Name,
-		Description: newConfig.Description,
-		AdvancedSecurity: newConfig.AdvancedSecurity,
-		DependencyGraph: newConfig.DependencyGraph,
-		DependencyGraphAutosubmitAction: newConfig.DependencyGraphAutosubmitAction,
-		DependencyGraphAutosubmitActionOptions: newConfig.DependencyGraphAutosubmitActionOptions,
-		DependabotAlerts: newConfig.DependabotAlerts,
-		DependabotSecurityUpdates: newConfig.DependabotSecurityUpdates,
-		CodeScanningDefaultSetup: newConfig.CodeScanningDefaultSetup,
-		CodeScanningDefaultSetupOptions: newConfig.CodeScanningDefaultSetupOptions,
-		SecretScanning: newConfig.SecretScanning,
-		SecretScanningPushProtection: newConfig.SecretScanningPushProtection,
-		SecretScanningValidityChecks: newConfig.SecretScanningValidityChecks,
-		SecretScanningNonProviderPatterns: newConfig.SecretScanningNonProviderPatterns,
-		SecretScanningGenericSecrets: newConfig.SecretScanningGenericSecrets,
-		SecretScanningDelegatedBypass: newConfig.SecretScanningDelegatedBypass,
-		SecretScanningDelegatedBypassOptions: newConfig.SecretScanningDelegatedBypassOptions,
-		SecretScanningDelegatedAlertDismissal: newConfig.SecretScanningDelegatedAlertDismissal,
-		PrivateVulnerabilityReporting: newConfig.PrivateVulnerabilityReporting,
-		Enforcement: newConfig.Enforcement,
-	DefaultForNewRepos: newConfig.DefaultForNewRepos,
+
+	if !*yes {
+		fmt.Print("Apply these changes? (y/N): ")
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if answer != "y\n" && answer != "Y\n" {
+			fmt.Println("Aborted.")
+			return
+		}
 	}
+
+
+	// Only the fields set in the YAML are sent, GitHub keeps the others as they are. ID and default_for_new_repos
+	// are not part of the body.
+	reqBody := codesecurity.Fields(newConfig)
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		log.Fatalf("Failed to marshal JSON: %v", err)
@@ -227,144 +235,112 @@ func main() {
 		}
 	}
 
-	var updateReq *http.Request
-	if configID != 0 {
-		// PATCH to update existing config using integer ID
-		var patchURL string
-		switch githubEndpoint {
-		case "GHEC":
-			patchURL = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations/%d", *org, configID)
-		case "GHES":
-			patchURL = fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d", *ghesURL, *org, configID)
-		default:
-			log.Fatalf("GITHUB_ENDPOINT environment variable must be set to either GHEC or GHES, or left unset for GHES as default")
-		}
-	} else {
-		// POST to create new config
-		updateReq, err = http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	// Nothing is changed unless the state it replaces can be restored with config.go revert
+	if currentConfig.ID != 0 {
+		path, err := codesecurity.SaveHistory(*historyDir, *org, currentConfig, codesecurity.ChangedFields(changes), "update")
 		if err != nil {
-			log.Fatalf("Failed to create POST request: %v", err)
+			log.Fatalf("Failed to save the previous version to %s, nothing was changed: %v", *historyDir, err)
 		}
+		fmt.Printf("📚 Previous version saved to %s\n", path)
 	}
 
-	updateReq.Header.Set("Accept", "application/vnd.github+json")
-	updateReq.Header.Set("Authorization", "Bearer "+ token)
-	updateReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	updateReq.Header.Set("Content-Type", "application/json")
-	updateResp, err := client.Do(updateReq)
-	if err != nil {
-		log.Fatalf("Update request failed: %v", err)
-	}
-	defer updateResp.Body.Close()
-	updateBody, _ := ioutil.ReadAll(updateResp.Body)
-	if updateResp.StatusCode >= 200 && updateResp.StatusCode < 300 {
-		fmt.Println("Configuration updated successfully:")
-		fmt.Println(string(updateBody))
-	} else {
-		fmt.Fprintf(os.Stderr, "API error: %s\n%s\n", updateResp.Status, string(updateBody))
-		os.Exit(1)
-	}
-}
-
-
-func printYAMLDiff(a, b CodeSecurityConfig) bool {
-	aMap := structToJSONMap(a)
-	bMap := structToJSONMap(b)
-	// Remove fields that should not be compared
-	delete(aMap, "id")
-	delete(bMap, "id")
-	delete(aMap, "target_type")
-	delete(bMap, "target_type")
-	fmt.Println("  (Only fields with true value changes are shown)")
-	return diffMapRecursive(aMap, bMap, "")
-}
-
-func structToJSONMap(s interface{}) map[string]interface{} {
-	var m map[string]interface{}
-	j, err := json.Marshal(s)
-	if err != nil {
-		return map[string]interface{}{}
-	}
-	if err := json.Unmarshal(j, &m); err != nil {
-		return map[string]interface{}{}
-	}
-	return m
-}
-
-func diffMapRecursive(a, b map[string]interface{}, prefix string) bool {
-	changes := false
-	for k, bVal := range b {
-		if k == "id" || k == "target_type" {
-			continue
-		}
-		aVal, ok := a[k]
-		if !ok {
-			fmt.Printf("> %s%s: %v (added)\n", prefix, k, bVal)
-			changes = true
-			continue
+	actor := auditlog.Actor(client, apiBase, token)
+	if configChanged {
+		var updateReq *http.Request
+		entry := auditlog.Entry{Command: "update_org_config", Org: *org, Config: newConfig.Name, ConfigID: configID}
+		if len(violations) > 0 {
+			entry.PolicyViolations, entry.OverrideReason = violations, strings.TrimSpace(*overridePolicy)
 		}
-		switch bValTyped := bVal.(type) {
-		case map[string]interface{}:
-			aValMap, ok := aVal.(map[string]interface{})
-			if ok {
-				if diffMapRecursive(aValMap, bValTyped, prefix+k+": ") {
-					changes = true
-				}
-			} else {
-				fmt.Printf("> %s%s: %v (type changed)\n", prefix, k, bVal)
-				changes = true
+		if configID != 0 {
+			// PATCH to update existing config using integer ID
+			var patchURL string
+			switch githubEndpoint {
+			case "GHEC":
+				patchURL = fmt.Sprintf("https://api.github.com/orgs/%s/code-security/configurations/%d", *org, configID)
+			case "GHES":
+				patchURL = fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d", *ghesURL, *org, configID)
+			default:
+				log.Fatalf("GITHUB_ENDPOINT environment variable must be set to either GHEC or GHES, or left unset for GHES as default")
 			}
-		default:
-			if !jsonValuesEqual(aVal, bVal) {
-				fmt.Printf("> %s%s: %v -> %v\n", prefix, k, aVal, bVal)
-				changes = true
+			updateReq, err = http.NewRequest("PATCH", patchURL, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				log.Fatalf("Failed to create PATCH request: %v", err)
 			}
+			entry.Action, entry.Method, entry.URL, entry.Before = "update_configuration", "PATCH", patchURL, currentConfig
+		} else {
+			// POST to create new config
+			updateReq, err = http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				log.Fatalf("Failed to create POST request: %v", err)
+			}
+			entry.Action, entry.Method, entry.URL = "create_configuration", "POST", url
 		}
-	}
-	return changes
-}
-
-func jsonValuesEqual(a, b interface{}) bool {
-	// Handle nil/null
-	if a == nil && b == nil {
-		return true
-	}
-	if a == nil || b == nil {
-		return false
-	}
-	// Compare as strings for simple types
-	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
-}
+		entry.Actor = actor
 
-func structToMap(s interface{}) map[string]interface{} {
-	var m map[string]interface{}
-	// Marshal to YAML then unmarshal to map for normalization
-	y, err := yaml.Marshal(s)
-	if err != nil {
-		return map[string]interface{}{}
-	}
-	if err := yaml.Unmarshal(y, &m); err != nil {
-		return map[string]interface{}{}
+		updateReq.Header.Set("Accept", "application/vnd.github+json")
+		updateReq.Header.Set("Authorization", "Bearer "+ token)
+		updateReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		updateReq.Header.Set("Content-Type", "application/json")
+		updateResp, err := client.Do(updateReq)
+		if err != nil {
+			entry.After, entry.Error = reqBody, err.Error()
+			auditlog.Append(auditlog.Path(*auditLog), entry)
+			log.Fatalf("Update request failed: %v", err)
+		}
+		defer updateResp.Body.Close()
+		updateBody, _ := ioutil.ReadAll(updateResp.Body)
+		entry.Status = updateResp.StatusCode
+		if updateResp.StatusCode >= 200 && updateResp.StatusCode < 300 {
+			entry.After = auditlog.Body(updateBody)
+			auditlog.Append(auditlog.Path(*auditLog), entry)
+			fmt.Println("Configuration updated successfully:")
+			fmt.Println(string(updateBody))
+			if configID == 0 {
+				// GHEC returns the configuration, GHES wraps it in "value"
+				var created struct {
+					ID    int `json:"id"`
+					Value struct {
+						ID int `json:"id"`
+					} `json:"value"`
+				}
+				json.Unmarshal(updateBody, &created)
+				configID = created.ID
+				if configID == 0 {
+					configID = created.Value.ID
+				}
+			}
+		} else {
+			entry.After, entry.Error = reqBody, strings.TrimSpace(string(updateBody))
+			auditlog.Append(auditlog.Path(*auditLog), entry)
+			fmt.Fprintf(os.Stderr, "API error: %s\n%s\n", updateResp.Status, string(updateBody))
+			os.Exit(1)
+		}
 	}
-	return m
-}
 
-func trimYAMLLine(s string) string {
-	// Remove leading/trailing whitespace and ignore empty lines
-	return strings.TrimSpace(s)
-}
-
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
+	if defaultChanged {
+		if configID == 0 {
+			log.Fatalf("Could not determine the ID of '%s' to set default_for_new_repos", newConfig.Name)
 		}
+		defaultsURL := fmt.Sprintf("%s/orgs/%s/code-security/configurations/%d/defaults", apiBase, *org, configID)
+		payload := map[string]string{"default_for_new_repos": newConfig.DefaultForNewRepos}
+		status, respBody, err := ghapi.Send(client, "PUT", defaultsURL, token, payload)
+		entry := auditlog.Entry{Actor: actor, Command: "update_org_config", Action: "set_default_for_new_repos", Org: *org, Config: newConfig.Name,
+			Method: "PUT", URL: defaultsURL, ConfigID: configID, Status: status, After: payload}
+		if currentConfig.DefaultForNewRepos != "" {
+			entry.Before = map[string]string{"default_for_new_repos": currentConfig.DefaultForNewRepos}
+		}
+		if len(violations) > 0 {
+			entry.PolicyViolations, entry.OverrideReason = violations, strings.TrimSpace(*overridePolicy)
+		}
+		if err != nil {
+			entry.Error = strings.TrimSpace(string(respBody))
+			if entry.Error == "" {
+				entry.Error = err.Error()
+			}
+			auditlog.Append(auditlog.Path(*auditLog), entry)
+			log.Fatalf("Failed to set default_for_new_repos: %v", err)
+		}
+		auditlog.Append(auditlog.Path(*auditLog), entry)
+		fmt.Printf("✅ default_for_new_repos set to %s\n", newConfig.DefaultForNewRepos)
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
-	}
-	return lines
 }